@@ -3,6 +3,8 @@ package def
 import (
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 )
 
 var (
@@ -20,6 +22,15 @@ var (
 	ErrCanNotSetSliceAsMapKey = fmt.Errorf("%wcan not set slice as map key", ErrMsgpack)
 	ErrCanNotSetMapAsMapKey   = fmt.Errorf("%wcan not set map as map key", ErrMsgpack)
 	ErrValueOutOfRange        = fmt.Errorf("%wvalue out of range", ErrMsgpack)
+	ErrFloatTruncated         = fmt.Errorf("%wfloat would be truncated", ErrMsgpack)
+	ErrNotCanonical           = fmt.Errorf("%wnot canonical", ErrMsgpack)
+	ErrMaxDepthExceeded       = fmt.Errorf("%wexceeded max depth", ErrMsgpack)
+	ErrMaxStringLenExceeded   = fmt.Errorf("%wexceeded max string length", ErrMsgpack)
+	ErrMaxBinLenExceeded      = fmt.Errorf("%wexceeded max bin length", ErrMsgpack)
+	ErrMaxArrayLenExceeded    = fmt.Errorf("%wexceeded max array length", ErrMsgpack)
+	ErrMaxMapLenExceeded      = fmt.Errorf("%wexceeded max map length", ErrMsgpack)
+	ErrMaxTotalBytesExceeded  = fmt.Errorf("%wexceeded max total bytes", ErrMsgpack)
+	ErrExtTypeMismatch        = fmt.Errorf("%wextension type mismatch", ErrMsgpack)
 
 	// encoding errors
 
@@ -29,4 +40,152 @@ var (
 	ErrUnsupportedType       = fmt.Errorf("%wunsupported type", ErrMsgpack)
 	ErrUnsupportedLength     = fmt.Errorf("%wunsupported length", ErrMsgpack)
 	ErrNotMatchLastIndex     = fmt.Errorf("%wnot match last index", ErrMsgpack)
+	ErrCycle                 = fmt.Errorf("%wencountered a cycle", ErrMsgpack)
 )
+
+// DecodeError describes the value that failed to decode.
+type DecodeError struct {
+	// Offset is the position of the value in the data or stream.
+	Offset int
+	// Path locates the value from the decoded type, such as
+	// Order.Items[3].Price. Elements are written as [i] and map values
+	// as [key]. It is empty when the top-level value failed. Slices and
+	// maps of basic types such as []int are decoded in bulk and report
+	// the container itself.
+	Path string
+	// Code is the format byte of the value, or zero when the data ended
+	// before it.
+	Code byte
+	// Type is the Go type the value was decoded as.
+	Type reflect.Type
+	// Err is the underlying error.
+	Err error
+
+	elems pathElems
+}
+
+func (e *DecodeError) Error() string {
+	path := e.elems.join() + e.Path
+	if path == "" {
+		path = "value"
+	}
+	return fmt.Sprintf("decoding %s as %v at offset %d (code %#02x): %v", path, e.Type, e.Offset, e.Code, e.Err)
+}
+
+// PrependPath adds elem in front of the path. The elements are joined
+// into Path once by ResolvePath.
+func (e *DecodeError) PrependPath(elem string) {
+	e.elems = append(e.elems, elem)
+}
+
+// ResolvePath sets Path to root followed by the elements added by
+// PrependPath and the former Path. Only the outermost and innermost
+// elements of very deep paths are kept.
+func (e *DecodeError) ResolvePath(root string) {
+	e.Path = root + e.elems.join() + e.Path
+	e.elems = nil
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports that every DecodeError is a msgpack error, including
+// those caused by the reader.
+func (e *DecodeError) Is(target error) bool {
+	return target == ErrMsgpack
+}
+
+// DecodeErrors lists the values that failed to decode and were skipped
+// by best-effort decoding, in the order they were met.
+type DecodeErrors []*DecodeError
+
+func (e DecodeErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d values failed to decode: %s", len(e), strings.Join(msgs, "; "))
+}
+
+func (e DecodeErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// Is reports that DecodeErrors is a msgpack error.
+func (e DecodeErrors) Is(target error) bool {
+	return target == ErrMsgpack
+}
+
+// EncodeError describes the value that failed to encode.
+type EncodeError struct {
+	// Path locates the value from the encoded type, such as
+	// Config.Hooks[0].Run. It is written like DecodeError.Path.
+	Path string
+	// Type is the Go type of the value.
+	Type reflect.Type
+	// Err is the underlying error.
+	Err error
+
+	elems pathElems
+}
+
+func (e *EncodeError) Error() string {
+	path := e.elems.join() + e.Path
+	if path == "" {
+		path = "value"
+	}
+	return fmt.Sprintf("encoding %s of type %v: %v", path, e.Type, e.Err)
+}
+
+// PrependPath adds elem in front of the path. The elements are joined
+// into Path once by ResolvePath.
+func (e *EncodeError) PrependPath(elem string) {
+	e.elems = append(e.elems, elem)
+}
+
+// ResolvePath sets Path to root followed by the elements added by
+// PrependPath and the former Path. Only the outermost and innermost
+// elements of very deep paths are kept.
+func (e *EncodeError) ResolvePath(root string) {
+	e.Path = root + e.elems.join() + e.Path
+	e.elems = nil
+}
+
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports that every EncodeError is a msgpack error, including
+// those caused by the writer.
+func (e *EncodeError) Is(target error) bool {
+	return target == ErrMsgpack
+}
+
+// pathEdgeElems is the number of elements kept at each end of a path.
+const pathEdgeElems = 32
+
+// pathElems holds the elements of a path from the innermost one, as they
+// are added while the error returns through the nested values.
+type pathElems []string
+
+// join returns the elements from the outermost one. The middle of a path
+// of more than twice pathEdgeElems elements is left out.
+func (p pathElems) join() string {
+	if len(p) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for i := len(p) - 1; i >= 0; i-- {
+		if len(p) > 2*pathEdgeElems && i == len(p)-1-pathEdgeElems {
+			fmt.Fprintf(&b, "...(%d elements)...", len(p)-2*pathEdgeElems)
+			i = pathEdgeElems - 1
+		}
+		b.WriteString(p[i])
+	}
+	return b.String()
+}
@@ -6,3 +6,17 @@ import (
 
 // Error is used in all msgpack error as the based error.
 var Error = def.ErrMsgpack
+
+// DecodeError describes the value that failed to decode: its offset,
+// path, format code and Go type. It wraps the underlying error and
+// satisfies errors.Is(err, Error).
+type DecodeError = def.DecodeError
+
+// DecodeErrors lists the values skipped by DecodeOptions.ContinueOnError.
+// errors.As finds each of them as a *DecodeError.
+type DecodeErrors = def.DecodeErrors
+
+// EncodeError describes the value that failed to encode: its path and
+// Go type. It wraps the underlying error and satisfies
+// errors.Is(err, Error).
+type EncodeError = def.EncodeError
@@ -0,0 +1,299 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shamaton/msgpack/v3"
+	"github.com/shamaton/msgpack/v3/def"
+	tu "github.com/shamaton/msgpack/v3/internal/common/testutil"
+)
+
+type decodeErrorItem struct {
+	Name  string
+	Price float64
+}
+
+type decodeErrorOrder struct {
+	ID    int
+	Items []decodeErrorItem
+	Tags  map[string]int
+	Extra any
+}
+
+type decodeErrorBadItem struct {
+	Name  string
+	Price any
+}
+
+type decodeErrorBadOrder struct {
+	ID    int
+	Items []decodeErrorBadItem
+}
+
+func decodeBoth(t *testing.T, o msgpack.DecodeOptions, data []byte, v any) []*msgpack.DecodeError {
+	t.Helper()
+
+	var errs []*msgpack.DecodeError
+	for _, err := range []error{
+		o.Unmarshal(data, v),
+		o.UnmarshalRead(bytes.NewReader(data), v),
+	} {
+		tu.IsError(t, err, msgpack.Error)
+		var de *msgpack.DecodeError
+		if !errors.As(err, &de) {
+			t.Fatalf("not a DecodeError: %v", err)
+		}
+		errs = append(errs, de)
+	}
+	return errs
+}
+
+func TestDecodeError(t *testing.T) {
+	t.Run("StructPath", func(t *testing.T) {
+		in := decodeErrorBadOrder{ID: 1}
+		for i := 0; i < 5; i++ {
+			in.Items = append(in.Items, decodeErrorBadItem{Name: "n", Price: 1.5})
+		}
+		in.Items[3].Price = "bad"
+
+		for _, asArray := range []bool{false, true} {
+			b, err := msgpack.EncodeOptions{StructAsArray: asArray}.Marshal(in)
+			tu.NoError(t, err)
+			offset := bytes.Index(b, []byte{0xa3, 'b', 'a', 'd'})
+
+			var out decodeErrorOrder
+			o := msgpack.DecodeOptions{StructAsArray: asArray}
+			for _, de := range decodeBoth(t, o, b, &out) {
+				tu.Equal(t, de.Path, "decodeErrorOrder.Items[3].Price")
+				tu.Equal(t, de.Offset, offset)
+				tu.Equal(t, de.Code, byte(0xa3))
+				tu.Equal(t, de.Type, reflect.TypeOf(float64(0)))
+				tu.IsError(t, de, def.ErrCanNotDecode)
+				tu.ErrorContains(t, de, "decodeErrorOrder.Items[3].Price as float64")
+			}
+		}
+	})
+
+	t.Run("MapValue", func(t *testing.T) {
+		b, err := msgpack.Marshal(map[string]any{"a": 1, "b": "x"})
+		tu.NoError(t, err)
+
+		var out map[string]*int
+		for _, de := range decodeBoth(t, msgpack.DecodeOptions{}, b, &out) {
+			tu.Equal(t, de.Path, `["b"]`)
+			tu.Equal(t, de.Code, byte(0xa1))
+			tu.Equal(t, de.Type, reflect.TypeOf(0))
+		}
+	})
+
+	t.Run("Interface", func(t *testing.T) {
+		// [1, {"k": 0xc1}], a code that is never used
+		b := []byte{0x92, 0x01, 0x81, 0xa1, 'k', 0xc1}
+
+		var out any
+		for _, de := range decodeBoth(t, msgpack.DecodeOptions{}, b, &out) {
+			tu.Equal(t, de.Path, `[1]["k"]`)
+			tu.Equal(t, de.Offset, 5)
+			tu.Equal(t, de.Code, byte(0xc1))
+		}
+	})
+
+	t.Run("TopLevel", func(t *testing.T) {
+		var out int
+		for _, de := range decodeBoth(t, msgpack.DecodeOptions{}, []byte{0xc0 + 1}, &out) {
+			tu.Equal(t, de.Path, "")
+			tu.Equal(t, de.Offset, 0)
+			tu.Equal(t, de.Code, byte(0xc1))
+		}
+	})
+
+	t.Run("DeepPath", func(t *testing.T) {
+		// arrays nested far deeper than a path keeps
+		b := append(bytes.Repeat([]byte{0x91}, 10000), 0xc1)
+
+		var out any
+		for _, de := range decodeBoth(t, msgpack.DecodeOptions{}, b, &out) {
+			tu.Equal(t, strings.Count(de.Path, "[0]"), 64)
+			tu.ErrorContains(t, de, "[0]...(9936 elements)...[0]")
+			if len(de.Error()) > 1000 {
+				t.Fatalf("too long error: %d bytes", len(de.Error()))
+			}
+		}
+	})
+
+	t.Run("UnexpectedEnd", func(t *testing.T) {
+		b, err := msgpack.Marshal([]string{"a", "bc"})
+		tu.NoError(t, err)
+
+		var out [2]string
+		err = msgpack.DecodeOptions{}.UnmarshalRead(bytes.NewReader(b[:len(b)-2]), &out)
+		tu.IsError(t, err, msgpack.Error)
+		tu.IsError(t, err, io.EOF)
+		var de *msgpack.DecodeError
+		if !errors.As(err, &de) {
+			t.Fatalf("not a DecodeError: %v", err)
+		}
+		tu.Equal(t, de.Path, "[1]")
+		tu.Equal(t, de.Offset, 3)
+	})
+}
+
+type encodeErrorHook struct {
+	Name string
+	Run  func()
+}
+
+type encodeErrorConfig struct {
+	Name  string
+	Hooks []encodeErrorHook
+	Opts  map[string]any
+}
+
+func encodeBoth(t *testing.T, o msgpack.EncodeOptions, v any) []*msgpack.EncodeError {
+	t.Helper()
+
+	_, err1 := o.Marshal(v)
+	err2 := o.MarshalWrite(&bytes.Buffer{}, v)
+
+	var errs []*msgpack.EncodeError
+	for _, err := range []error{err1, err2} {
+		tu.IsError(t, err, msgpack.Error)
+		tu.IsError(t, err, def.ErrUnsupportedType)
+		var ee *msgpack.EncodeError
+		if !errors.As(err, &ee) {
+			t.Fatalf("not an EncodeError: %v", err)
+		}
+		errs = append(errs, ee)
+	}
+	return errs
+}
+
+func TestEncodeError(t *testing.T) {
+	t.Run("StructPath", func(t *testing.T) {
+		v := encodeErrorConfig{
+			Hooks: []encodeErrorHook{{Name: "a"}, {Name: "b", Run: func() {}}},
+		}
+		for _, o := range []msgpack.EncodeOptions{{}, {StructAsArray: true}, {Canonical: true}} {
+			for _, ee := range encodeBoth(t, o, &v) {
+				tu.Equal(t, ee.Path, "encodeErrorConfig.Hooks[0].Run")
+				tu.Equal(t, ee.Type, reflect.TypeOf(func() {}))
+				tu.ErrorContains(t, ee, "encodeErrorConfig.Hooks[0].Run of type func()")
+			}
+		}
+	})
+
+	t.Run("MapValue", func(t *testing.T) {
+		v := encodeErrorConfig{Opts: map[string]any{"ch": make(chan int)}}
+		for _, o := range []msgpack.EncodeOptions{{}, {Canonical: true}} {
+			for _, ee := range encodeBoth(t, o, v) {
+				tu.Equal(t, ee.Path, `encodeErrorConfig.Opts["ch"]`)
+				tu.Equal(t, ee.Type, reflect.TypeOf(make(chan int)))
+			}
+		}
+	})
+
+	t.Run("TopLevel", func(t *testing.T) {
+		for _, ee := range encodeBoth(t, msgpack.EncodeOptions{}, make(chan int)) {
+			tu.Equal(t, ee.Path, "")
+			tu.Equal(t, ee.Type, reflect.TypeOf(make(chan int)))
+		}
+	})
+}
+
+type continueItem struct {
+	ID    int
+	Price float64
+}
+
+type continueOrder struct {
+	Name  string
+	Count int
+	Items []continueItem
+	Attrs map[string]int8
+	Refs  map[string]*int
+	Note  string
+}
+
+func TestContinueOnError(t *testing.T) {
+	in := map[string]any{
+		"Name":  "order",
+		"Count": "three",
+		"Items": []any{
+			map[string]any{"ID": 1, "Price": 1.5},
+			map[string]any{"ID": "x", "Price": 2.5},
+			map[string]any{"ID": 3, "Price": []int{1}},
+		},
+		"Attrs": map[string]any{"a": 1, "b": 300},
+		"Refs":  map[string]any{"a": 1, "b": "x"},
+		"Note":  "done",
+	}
+	b, err := msgpack.Marshal(in)
+	tu.NoError(t, err)
+
+	t.Run("Disabled", func(t *testing.T) {
+		var out continueOrder
+		err := msgpack.DecodeOptions{}.Unmarshal(b, &out)
+		var errs msgpack.DecodeErrors
+		tu.Equal(t, errors.As(err, &errs), false)
+		tu.IsError(t, err, msgpack.Error)
+	})
+
+	t.Run("Enabled", func(t *testing.T) {
+		var out continueOrder
+		err := msgpack.DecodeOptions{ContinueOnError: true}.Unmarshal(b, &out)
+		tu.IsError(t, err, msgpack.Error)
+		tu.IsError(t, err, def.ErrCanNotDecode)
+
+		var errs msgpack.DecodeErrors
+		if !errors.As(err, &errs) {
+			t.Fatalf("not DecodeErrors: %v", err)
+		}
+		paths := map[string]bool{}
+		for _, e := range errs {
+			paths[e.Path] = true
+		}
+		tu.EqualMap(t, paths, map[string]bool{
+			"continueOrder.Count":          true,
+			"continueOrder.Items[1].ID":    true,
+			"continueOrder.Items[2].Price": true,
+			"continueOrder.Attrs":          true,
+			`continueOrder.Refs["b"]`:      true,
+		})
+
+		tu.Equal(t, out.Name, "order")
+		tu.Equal(t, out.Note, "done")
+		tu.Equal(t, out.Count, 0)
+		tu.Equal(t, out.Items, []continueItem{{ID: 1, Price: 1.5}, {Price: 2.5}, {ID: 3}})
+		tu.Equal(t, out.Attrs, map[string]int8(nil))
+		tu.Equal(t, len(out.Refs), 1)
+		tu.Equal(t, *out.Refs["a"], 1)
+	})
+
+	t.Run("TopLevel", func(t *testing.T) {
+		var out int
+		err := msgpack.DecodeOptions{ContinueOnError: true}.Unmarshal([]byte{0xa1, 'x'}, &out)
+		var errs msgpack.DecodeErrors
+		if !errors.As(err, &errs) {
+			t.Fatalf("not DecodeErrors: %v", err)
+		}
+		tu.Equal(t, len(errs), 1)
+	})
+
+	t.Run("Malformed", func(t *testing.T) {
+		// the bad value cannot be skipped when the data ends inside it
+		var out continueOrder
+		err := msgpack.DecodeOptions{ContinueOnError: true}.Unmarshal(b[:len(b)-3], &out)
+		tu.IsError(t, err, msgpack.Error)
+
+		// the data ends after a key, before its value
+		for _, opt := range []msgpack.DecodeOptions{{}, {ContinueOnError: true}} {
+			err = opt.Unmarshal([]byte{0x81, 0xa4, 'N', 'o', 't', 'e'}, &out)
+			tu.IsError(t, err, def.ErrTooShortBytes)
+		}
+	})
+}
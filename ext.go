@@ -0,0 +1,140 @@
+package msgpack
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/ext"
+	"github.com/shamaton/msgpack/v3/internal/common"
+)
+
+// RegisterExt adds coders for values of T as extensions of type code, for
+// both the byte and the stream functions. marshal returns the data of a
+// value, which is written in the smallest of the fixext and ext formats,
+// and unmarshal returns the value of the data. unmarshal must not keep
+// data after it returns. T must be a struct or a named type, other than
+// a pointer, so that nil pointers to values of T are encoded as nil.
+func RegisterExt[T any](code int8, marshal func(T) ([]byte, error), unmarshal func([]byte) (T, error)) error {
+	if marshal == nil || unmarshal == nil {
+		return fmt.Errorf("marshal and unmarshal of extension type %d must not be nil", code)
+	}
+	if t := reflect.TypeFor[T](); t.Kind() == reflect.Ptr || t.Kind() != reflect.Struct && !common.IsExtType(t) {
+		return fmt.Errorf("extension type %d can not be registered for %v, which is not a struct or a named type", code, t)
+	}
+	c := &funcExt[T]{code: code, marshal: marshal, unmarshal: unmarshal}
+	if err := AddExtCoder(&funcExtEncoder[T]{c}, &funcExtDecoder[T]{c}); err != nil {
+		return err
+	}
+	return AddExtStreamCoder(&funcExtStreamEncoder[T]{c}, &funcExtStreamDecoder[T]{c})
+}
+
+// UnregisterExt removes the coders that RegisterExt added for T and code.
+func UnregisterExt[T any](code int8) error {
+	c := &funcExt[T]{code: code}
+	if err := RemoveExtCoder(&funcExtEncoder[T]{c}, &funcExtDecoder[T]{c}); err != nil {
+		return err
+	}
+	return RemoveExtStreamCoder(&funcExtStreamEncoder[T]{c}, &funcExtStreamDecoder[T]{c})
+}
+
+// funcExt holds the functions of an extension type added by RegisterExt.
+type funcExt[T any] struct {
+	code      int8
+	marshal   func(T) ([]byte, error)
+	unmarshal func([]byte) (T, error)
+}
+
+func (c *funcExt[T]) Code() int8 {
+	return c.code
+}
+
+func (c *funcExt[T]) Type() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+// EncodeExt returns the header and the data of value.
+func (c *funcExt[T]) EncodeExt(value reflect.Value) ([]byte, error) {
+	data, err := c.marshal(value.Interface().(T))
+	if err != nil {
+		return nil, err
+	}
+	var buf [6]byte
+	header, err := common.ExtHeader(buf[:0], c.code, len(data))
+	if err != nil {
+		return nil, err
+	}
+	return append(header, data...), nil
+}
+
+func (c *funcExt[T]) decode(data []byte) (any, error) {
+	v, err := c.unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: extension type %d: %w", def.ErrCanNotDecode, c.code, err)
+	}
+	return v, nil
+}
+
+type funcExtEncoder[T any] struct{ *funcExt[T] }
+
+var (
+	_ ext.Encoder            = (*funcExtEncoder[int])(nil)
+	_ common.ExtBytesEncoder = (*funcExtEncoder[int])(nil)
+)
+
+func (e *funcExtEncoder[T]) CalcByteSize(value reflect.Value) (int, error) {
+	b, err := e.EncodeExt(value)
+	return len(b), err
+}
+
+func (e *funcExtEncoder[T]) WriteToBytes(value reflect.Value, offset int, bytes *[]byte) int {
+	// the encoder writes the bytes of EncodeExt instead, and an error
+	// leaves offset short of the calculated size, which it reports
+	b, err := e.EncodeExt(value)
+	if err != nil {
+		return offset
+	}
+	return offset + copy((*bytes)[offset:], b)
+}
+
+type funcExtDecoder[T any] struct{ *funcExt[T] }
+
+var _ ext.Decoder = (*funcExtDecoder[int])(nil)
+
+func (d *funcExtDecoder[T]) IsType(offset int, data *[]byte) bool {
+	typ, _, _, ok := common.ExtData(offset, *data)
+	return ok && typ == d.code
+}
+
+func (d *funcExtDecoder[T]) AsValue(offset int, _ reflect.Kind, data *[]byte) (any, int, error) {
+	_, b, end, _ := common.ExtData(offset, *data)
+	v, err := d.decode(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, end, nil
+}
+
+type funcExtStreamEncoder[T any] struct{ *funcExt[T] }
+
+var _ ext.StreamEncoder = (*funcExtStreamEncoder[int])(nil)
+
+func (e *funcExtStreamEncoder[T]) Write(w ext.StreamWriter, value reflect.Value) error {
+	b, err := e.EncodeExt(value)
+	if err != nil {
+		return err
+	}
+	return w.WriteBytes(b)
+}
+
+type funcExtStreamDecoder[T any] struct{ *funcExt[T] }
+
+var _ ext.StreamDecoder = (*funcExtStreamDecoder[int])(nil)
+
+func (d *funcExtStreamDecoder[T]) IsType(code byte, innerType int8, _ int) bool {
+	return common.IsExtCode(code) && innerType == d.code
+}
+
+func (d *funcExtStreamDecoder[T]) ToValue(_ byte, data []byte, _ reflect.Kind) (any, error) {
+	return d.decode(data)
+}
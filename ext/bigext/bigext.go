@@ -0,0 +1,82 @@
+// Package bigext provides extension coders for big.Int, big.Float and
+// big.Rat values, including pointers to them.
+//
+// Each value is an extension of its own type code in the smallest of the
+// fixext and ext formats that holds its data. The data layouts are:
+//
+//	big.Int   (code 0x10): sign byte (0 or 1 when negative),
+//	                       big-endian magnitude
+//	big.Float (code 0x11): rounding mode byte, precision uint32,
+//	                       decimal text in the shortest form for the precision
+//	big.Rat   (code 0x12): sign byte (0 or 1 when negative),
+//	                       numerator length uint32, numerator magnitude,
+//	                       denominator magnitude
+//
+// All integers in the layouts are big-endian. When SetEncodedIntAsPlain is
+// enabled, a big.Int that fits in an int64 or a uint64 is written as a
+// plain integer instead. The big.Int decoders accept plain integers in
+// either case.
+//
+// big.Float values of more than MaxFloatPrec bits of precision are neither
+// encoded nor decoded, so that decoding untrusted data does bounded work.
+package bigext
+
+import (
+	"github.com/shamaton/msgpack/v3"
+	"github.com/shamaton/msgpack/v3/ext"
+	"github.com/shamaton/msgpack/v3/internal/common"
+)
+
+// Default extension type codes.
+const (
+	IntCode   int8 = 0x10
+	FloatCode int8 = 0x11
+	RatCode   int8 = 0x12
+)
+
+var encodeIntAsPlain = false
+
+// SetCodes sets the extension type codes of big.Int, big.Float and
+// big.Rat values. It must be called before the coders are registered.
+func SetCodes(intCode, floatCode, ratCode int8) {
+	intFormat.code, floatFormat.code, ratFormat.code = intCode, floatCode, ratCode
+}
+
+// SetEncodedIntAsPlain sets big.Int values that fit in an int64 or a
+// uint64 to be encoded as plain integers.
+func SetEncodedIntAsPlain(b bool) {
+	encodeIntAsPlain = b
+}
+
+// Register adds the coders of big.Int, big.Float and big.Rat to msgpack,
+// for both the byte and the stream functions.
+func Register() error {
+	coders := []struct {
+		e  ext.Encoder
+		d  ext.DecoderV2
+		se ext.StreamEncoder
+		sd ext.StreamDecoderV2
+	}{
+		{e: IntEncoder, d: IntDecoder, se: IntStreamEncoder, sd: IntStreamDecoder},
+		{e: FloatEncoder, d: FloatDecoder, se: FloatStreamEncoder, sd: FloatStreamDecoder},
+		{e: RatEncoder, d: RatDecoder, se: RatStreamEncoder, sd: RatStreamDecoder},
+	}
+	for _, c := range coders {
+		if err := msgpack.AddExtCoderV2(c.e, c.d); err != nil {
+			return err
+		}
+		if err := msgpack.AddExtStreamCoderV2(c.se, c.sd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// appendExt appends data as an extension of type code.
+func appendExt(b []byte, code int8, data []byte) ([]byte, error) {
+	b, err := common.ExtHeader(b, code, len(data))
+	if err != nil {
+		return nil, err
+	}
+	return append(b, data...), nil
+}
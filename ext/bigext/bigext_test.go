@@ -0,0 +1,274 @@
+package bigext_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shamaton/msgpack/v3"
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/ext/bigext"
+	tu "github.com/shamaton/msgpack/v3/internal/common/testutil"
+)
+
+func TestMain(m *testing.M) {
+	if err := bigext.Register(); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
+
+// roundTrip encodes in with the byte and stream functions and decodes
+// the data into a new value of the type of in with both.
+func roundTrip[T any](t *testing.T, in T) ([]byte, T) {
+	t.Helper()
+
+	data, err := msgpack.Marshal(in)
+	tu.NoError(t, err)
+	buf := bytes.Buffer{}
+	tu.NoError(t, msgpack.MarshalWrite(&buf, in))
+	tu.EqualSlice(t, buf.Bytes(), data)
+
+	var out1, out2 T
+	tu.NoError(t, msgpack.Unmarshal(data, &out1))
+	tu.NoError(t, msgpack.UnmarshalRead(bytes.NewReader(data), &out2))
+	tu.Equal(t, reflect.DeepEqual(out1, out2), true)
+	return data, out1
+}
+
+func bigInt(s string) *big.Int {
+	x, _ := new(big.Int).SetString(s, 0)
+	return x
+}
+
+func TestInt(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *big.Int
+		data []byte
+	}{
+		{name: "Zero", in: big.NewInt(0), data: []byte{def.Fixext1, 0x10, 0}},
+		{name: "Small", in: big.NewInt(258), data: []byte{def.Ext8, 3, 0x10, 0, 1, 2}},
+		{name: "Negative", in: big.NewInt(-1), data: []byte{def.Fixext2, 0x10, 1, 1}},
+		{name: "Large", in: bigInt("0x0102030405060708090a0b0c0d0e0f"), data: []byte{def.Fixext16, 0x10, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}},
+		{name: "LargeNegative", in: bigInt("-0x1" + string(bytes.Repeat([]byte("0"), 600))), data: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, out := roundTrip(t, tt.in)
+			if tt.data != nil {
+				tu.EqualSlice(t, data, tt.data)
+			}
+			tu.Equal(t, out.Cmp(tt.in), 0)
+		})
+	}
+
+	t.Run("Value", func(t *testing.T) {
+		type st struct {
+			V   big.Int
+			P   *big.Int
+			Nil *big.Int
+			S   []*big.Int
+		}
+		in := st{V: *big.NewInt(-7), P: bigInt("123456789012345678901234567890"), S: []*big.Int{big.NewInt(1), big.NewInt(2)}}
+		_, out := roundTrip(t, in)
+		tu.Equal(t, out.V.Cmp(&in.V), 0)
+		tu.Equal(t, out.P.Cmp(in.P), 0)
+		tu.Equal(t, out.Nil == nil, true)
+		tu.Equal(t, len(out.S), 2)
+		tu.Equal(t, out.S[1].Cmp(in.S[1]), 0)
+	})
+
+	t.Run("Interface", func(t *testing.T) {
+		in := bigInt("-123456789012345678901234567890")
+		data, err := msgpack.Marshal(in)
+		tu.NoError(t, err)
+		var v1, v2 any
+		tu.NoError(t, msgpack.Unmarshal(data, &v1))
+		tu.NoError(t, msgpack.UnmarshalRead(bytes.NewReader(data), &v2))
+		for _, v := range []any{v1, v2} {
+			x, ok := v.(*big.Int)
+			tu.Equal(t, ok, true)
+			tu.Equal(t, x.Cmp(in), 0)
+		}
+	})
+
+	t.Run("Destinations", func(t *testing.T) {
+		data := []byte{def.Ext8, 3, 0x10, 0, 1, 2}
+		var (
+			v big.Int
+			p *big.Int
+			s fmt.Stringer
+		)
+		for _, dst := range []any{&v, &p, &s} {
+			rv := reflect.ValueOf(dst).Elem()
+			end, err := bigext.IntDecoder.DecodeValue(0, rv, &data)
+			tu.NoError(t, err)
+			tu.Equal(t, end, len(data))
+			tu.NoError(t, bigext.IntStreamDecoder.DecodeValue(data[0], data[3:], rv))
+		}
+		tu.Equal(t, v.Int64(), 258)
+		tu.Equal(t, p.Int64(), 258)
+		tu.Equal(t, s.(*big.Int).Int64(), 258)
+
+		var r big.Rat
+		rv := reflect.ValueOf(&r).Elem()
+		_, err := bigext.IntDecoder.DecodeValue(0, rv, &data)
+		tu.IsError(t, err, def.ErrExtTypeMismatch)
+		err = bigext.IntStreamDecoder.DecodeValue(data[0], data[3:], rv)
+		tu.IsError(t, err, def.ErrExtTypeMismatch)
+	})
+}
+
+func TestIntAsPlain(t *testing.T) {
+	bigext.SetEncodedIntAsPlain(true)
+	defer bigext.SetEncodedIntAsPlain(false)
+
+	tests := []struct {
+		name string
+		in   *big.Int
+		data []byte
+	}{
+		{name: "FixInt", in: big.NewInt(5), data: []byte{5}},
+		{name: "NegativeFixInt", in: big.NewInt(-32), data: []byte{0xe0}},
+		{name: "Uint8", in: big.NewInt(200), data: []byte{def.Uint8, 200}},
+		{name: "Int16", in: big.NewInt(-300), data: []byte{def.Int16, 0xfe, 0xd4}},
+		{name: "Uint64", in: new(big.Int).SetUint64(math.MaxUint64), data: []byte{def.Uint64, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{name: "Int64", in: big.NewInt(math.MinInt64), data: []byte{def.Int64, 0x80, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "TooLarge", in: bigInt("0x10000000000000000"), data: []byte{def.Ext8, 10, 0x10, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, out := roundTrip(t, tt.in)
+			tu.EqualSlice(t, data, tt.data)
+			tu.Equal(t, out.Cmp(tt.in), 0)
+
+			// plain integers still decode as usual
+			if !bytes.Equal(data[:1], []byte{def.Ext8}) {
+				var v any
+				tu.NoError(t, msgpack.Unmarshal(data, &v))
+				_, isBig := v.(*big.Int)
+				tu.Equal(t, isBig, false)
+			}
+		})
+	}
+
+	t.Run("Field", func(t *testing.T) {
+		type st struct {
+			A big.Int
+			B *big.Int
+			C int
+		}
+		in := st{A: *big.NewInt(-3), B: big.NewInt(70000), C: 1}
+		_, out := roundTrip(t, in)
+		tu.Equal(t, out.A.Cmp(&in.A), 0)
+		tu.Equal(t, out.B.Cmp(in.B), 0)
+		tu.Equal(t, out.C, 1)
+	})
+}
+
+func TestFloat(t *testing.T) {
+	third := new(big.Float).SetPrec(200).Quo(big.NewFloat(1), big.NewFloat(3))
+	tests := []struct {
+		name string
+		in   *big.Float
+	}{
+		{name: "Zero", in: new(big.Float)},
+		{name: "NegativeZero", in: big.NewFloat(math.Copysign(0, -1))},
+		{name: "Float64", in: big.NewFloat(1.5)},
+		{name: "HighPrecision", in: third},
+		{name: "Huge", in: new(big.Float).SetMantExp(big.NewFloat(0.75), 1<<12)},
+		{name: "Inf", in: new(big.Float).SetInf(true)},
+		{name: "Mode", in: new(big.Float).SetMode(big.ToZero).SetPrec(10).SetFloat64(3.25)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, out := roundTrip(t, tt.in)
+			tu.Equal(t, out.Cmp(tt.in), 0)
+			tu.Equal(t, out.Signbit(), tt.in.Signbit())
+			tu.Equal(t, out.Prec(), tt.in.Prec())
+			tu.Equal(t, out.Mode(), tt.in.Mode())
+		})
+	}
+
+	t.Run("Data", func(t *testing.T) {
+		data, err := msgpack.Marshal(big.NewFloat(1.5))
+		tu.NoError(t, err)
+		tu.EqualSlice(t, data, []byte{def.Fixext8, 0x11, 0, 0, 0, 0, 53, '1', '.', '5'})
+	})
+}
+
+func TestRat(t *testing.T) {
+	tests := []struct {
+		name string
+		in   *big.Rat
+	}{
+		{name: "Zero", in: new(big.Rat)},
+		{name: "Integer", in: big.NewRat(5, 1)},
+		{name: "Negative", in: big.NewRat(-1, 3)},
+		{name: "Large", in: new(big.Rat).SetFrac(bigInt("123456789012345678901234567890"), bigInt("987654321098765432109876543211"))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, out := roundTrip(t, tt.in)
+			tu.Equal(t, out.Cmp(tt.in), 0)
+		})
+	}
+
+	t.Run("Data", func(t *testing.T) {
+		data, err := msgpack.Marshal(big.NewRat(-1, 3))
+		tu.NoError(t, err)
+		tu.EqualSlice(t, data, []byte{def.Ext8, 7, 0x12, 1, 0, 0, 0, 1, 1, 3})
+	})
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		v    any
+	}{
+		{name: "IntSign", data: []byte{def.Fixext2, 0x10, 2, 1}, v: new(big.Int)},
+		{name: "FloatText", data: []byte{def.Ext8, 6, 0x11, 0, 0, 0, 0, 53, 'x'}, v: new(big.Float)},
+		{name: "FloatHeader", data: []byte{def.Fixext2, 0x11, 0, 0}, v: new(big.Float)},
+		{name: "FloatMode", data: []byte{def.Ext8, 6, 0x11, 9, 0, 0, 0, 53, '1'}, v: new(big.Float)},
+		{name: "FloatPrec", data: floatData(0xfffffff0, "1e900000000"), v: new(big.Float)},
+		{name: "FloatTextLen", data: floatData(bigext.MaxFloatPrec, strings.Repeat("1", 1<<15)), v: new(big.Float)},
+		{name: "RatDenominator", data: []byte{def.Ext8, 6, 0x12, 0, 0, 0, 0, 1, 1}, v: new(big.Rat)},
+		{name: "RatLength", data: []byte{def.Ext8, 6, 0x12, 0, 0, 0, 0, 9, 1}, v: new(big.Rat)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tu.IsError(t, msgpack.Unmarshal(tt.data, tt.v), def.ErrCanNotDecode)
+			tu.IsError(t, msgpack.UnmarshalRead(bytes.NewReader(tt.data), tt.v), def.ErrCanNotDecode)
+		})
+	}
+}
+
+// floatData returns a big.Float extension of prec and text.
+func floatData(prec uint32, text string) []byte {
+	data := binary.BigEndian.AppendUint32([]byte{0}, prec)
+	data = append(data, text...)
+	b := binary.BigEndian.AppendUint32([]byte{def.Ext32}, uint32(len(data)))
+	return append(append(b, 0x11), data...)
+}
+
+func TestFloatLimits(t *testing.T) {
+	// the largest precision with a huge exponent decodes quickly
+	var x big.Float
+	data := floatData(bigext.MaxFloatPrec, "1e900000000")
+	tu.NoError(t, msgpack.Unmarshal(data, &x))
+	tu.NoError(t, msgpack.UnmarshalRead(bytes.NewReader(data), &x))
+	tu.Equal(t, x.IsInf(), true)
+
+	precise := new(big.Float).SetPrec(bigext.MaxFloatPrec + 1)
+	_, err := msgpack.Marshal(precise)
+	tu.IsError(t, err, def.ErrValueOutOfRange)
+	tu.IsError(t, msgpack.MarshalWrite(&bytes.Buffer{}, precise), def.ErrValueOutOfRange)
+}
@@ -0,0 +1,207 @@
+package bigext
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/ext"
+	"github.com/shamaton/msgpack/v3/internal/common"
+)
+
+// format describes how values of T are written as an extension.
+type format[T any] struct {
+	code int8
+	typ  reflect.Type
+
+	// appendTo appends the whole encoded value of x
+	appendTo func(b []byte, code int8, x *T) ([]byte, error)
+	// parse reads the extension data of a value
+	parse func(data []byte) (*T, error)
+	// parsePlain reads a plain integer when it is set
+	parsePlain func(code byte, data []byte) (*T, error)
+}
+
+func (f *format[T]) bytes(value reflect.Value) ([]byte, error) {
+	x := value.Interface().(T)
+	return f.appendTo(nil, f.code, &x)
+}
+
+// canSet reports whether x of a decoded value can be set to rv, which is
+// a T, a *T or an interface that *T implements.
+func (f *format[T]) canSet(rv reflect.Value) bool {
+	t := rv.Type()
+	pt := reflect.PointerTo(f.typ)
+	return t == f.typ || t == pt || t.Kind() == reflect.Interface && pt.Implements(t)
+}
+
+// set sets x to rv, which canSet accepts. Pointers and interfaces get x
+// itself.
+func (f *format[T]) set(rv reflect.Value, x *T) {
+	if rv.Type() == f.typ {
+		rv.Set(reflect.ValueOf(x).Elem())
+		return
+	}
+	rv.Set(reflect.ValueOf(x))
+}
+
+func (f *format[T]) mismatchError(rv reflect.Value) error {
+	return fmt.Errorf("%w: %v can not be decoded into %v", def.ErrExtTypeMismatch, f.typ, rv.Type())
+}
+
+func (f *format[T]) decodeError(code byte) error {
+	return fmt.Errorf("%w: code %x decoding %v", def.ErrCanNotDecode, code, f.typ)
+}
+
+type encoder[T any] struct {
+	ext.EncoderCommon
+	f *format[T]
+}
+
+var _ ext.Encoder = (*encoder[struct{}])(nil)
+
+func (e *encoder[T]) Code() int8 {
+	return e.f.code
+}
+
+func (e *encoder[T]) Type() reflect.Type {
+	return e.f.typ
+}
+
+func (e *encoder[T]) CalcByteSize(value reflect.Value) (int, error) {
+	b, err := e.f.bytes(value)
+	return len(b), err
+}
+
+func (e *encoder[T]) WriteToBytes(value reflect.Value, offset int, bytes *[]byte) int {
+	// CalcByteSize has reported values that can't be encoded
+	b, _ := e.f.bytes(value)
+	return e.SetBytes(b, offset, bytes)
+}
+
+type decoder[T any] struct {
+	f *format[T]
+}
+
+var (
+	_ ext.DecoderV2   = (*decoder[struct{}])(nil)
+	_ ext.IntAcceptor = (*decoder[struct{}])(nil)
+)
+
+func (d *decoder[T]) Code() int8 {
+	return d.f.code
+}
+
+// AcceptsInts reports whether plain integers are decoded too.
+func (d *decoder[T]) AcceptsInts() bool {
+	return d.f.parsePlain != nil
+}
+
+func (d *decoder[T]) IsType(offset int, data *[]byte) bool {
+	if typ, _, _, ok := common.ExtData(offset, *data); ok {
+		return typ == d.f.code
+	}
+	if d.f.parsePlain != nil {
+		_, _, _, ok := plainData(offset, *data)
+		return ok
+	}
+	return false
+}
+
+func (d *decoder[T]) DecodeValue(offset int, rv reflect.Value, data *[]byte) (int, error) {
+	if !d.f.canSet(rv) {
+		return 0, d.f.mismatchError(rv)
+	}
+	if typ, b, end, ok := common.ExtData(offset, *data); ok && typ == d.f.code {
+		x, err := d.f.parse(b)
+		if err != nil {
+			return 0, err
+		}
+		d.f.set(rv, x)
+		return end, nil
+	}
+	if code, b, end, ok := plainData(offset, *data); ok && d.f.parsePlain != nil {
+		x, err := d.f.parsePlain(code, b)
+		if err != nil {
+			return 0, err
+		}
+		d.f.set(rv, x)
+		return end, nil
+	}
+	if offset >= len(*data) {
+		return 0, def.ErrTooShortBytes
+	}
+	return 0, d.f.decodeError((*data)[offset])
+}
+
+type streamEncoder[T any] struct {
+	f *format[T]
+}
+
+var _ ext.StreamEncoder = (*streamEncoder[struct{}])(nil)
+
+func (e *streamEncoder[T]) Code() int8 {
+	return e.f.code
+}
+
+func (e *streamEncoder[T]) Type() reflect.Type {
+	return e.f.typ
+}
+
+func (e *streamEncoder[T]) Write(w ext.StreamWriter, value reflect.Value) error {
+	b, err := e.f.bytes(value)
+	if err != nil {
+		return err
+	}
+	return w.WriteBytes(b)
+}
+
+type streamDecoder[T any] struct {
+	f *format[T]
+}
+
+var (
+	_ ext.StreamDecoderV2 = (*streamDecoder[struct{}])(nil)
+	_ ext.IntAcceptor     = (*streamDecoder[struct{}])(nil)
+)
+
+func (d *streamDecoder[T]) Code() int8 {
+	return d.f.code
+}
+
+// AcceptsInts reports whether plain integers are decoded too.
+func (d *streamDecoder[T]) AcceptsInts() bool {
+	return d.f.parsePlain != nil
+}
+
+func (d *streamDecoder[T]) IsType(code byte, innerType int8, dataLength int) bool {
+	if common.IsExtCode(code) {
+		return innerType == d.f.code
+	}
+	if d.f.parsePlain != nil {
+		n, ok := plainLen(code)
+		return ok && n == dataLength
+	}
+	return false
+}
+
+func (d *streamDecoder[T]) DecodeValue(code byte, data []byte, rv reflect.Value) error {
+	if !d.f.canSet(rv) {
+		return d.f.mismatchError(rv)
+	}
+	var x *T
+	var err error
+	switch {
+	case common.IsExtCode(code):
+		x, err = d.f.parse(data)
+	case d.f.parsePlain != nil:
+		x, err = d.f.parsePlain(code, data)
+	default:
+		err = d.f.decodeError(code)
+	}
+	if err != nil {
+		return err
+	}
+	d.f.set(rv, x)
+	return nil
+}
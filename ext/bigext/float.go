@@ -0,0 +1,70 @@
+package bigext
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/shamaton/msgpack/v3/def"
+)
+
+var floatFormat = &format[big.Float]{
+	code:     FloatCode,
+	typ:      reflect.TypeOf(big.Float{}),
+	appendTo: appendFloat,
+	parse:    parseFloat,
+}
+
+// Coders of big.Float values.
+var (
+	FloatEncoder       = &encoder[big.Float]{f: floatFormat}
+	FloatDecoder       = &decoder[big.Float]{f: floatFormat}
+	FloatStreamEncoder = &streamEncoder[big.Float]{f: floatFormat}
+	FloatStreamDecoder = &streamDecoder[big.Float]{f: floatFormat}
+)
+
+const floatHeaderLen = 1 + 4
+
+// MaxFloatPrec is the largest precision of big.Float values that are
+// encoded and decoded, which bounds the work of decoding untrusted data.
+const MaxFloatPrec = 1 << 16
+
+// maxFloatTextLen bounds the text of a big.Float. The shortest text at
+// MaxFloatPrec has fewer than MaxFloatPrec/3 digits, besides its sign,
+// point and exponent.
+const maxFloatTextLen = MaxFloatPrec/3 + 32
+
+func appendFloat(b []byte, code int8, x *big.Float) ([]byte, error) {
+	if x.Prec() > MaxFloatPrec {
+		return nil, fmt.Errorf("%w: big.Float precision %d is over %d", def.ErrValueOutOfRange, x.Prec(), MaxFloatPrec)
+	}
+	data := make([]byte, floatHeaderLen, floatHeaderLen+16)
+	data[0] = byte(x.Mode())
+	binary.BigEndian.PutUint32(data[1:], uint32(x.Prec())) // #nosec G115 -- precision is at most MaxFloatPrec.
+	// the shortest text identifies x uniquely at its precision
+	data = x.Append(data, 'g', -1)
+	return appendExt(b, code, data)
+}
+
+func parseFloat(data []byte) (*big.Float, error) {
+	if len(data) < floatHeaderLen {
+		return nil, fmt.Errorf("%w: invalid big.Float data", def.ErrCanNotDecode)
+	}
+	mode := big.RoundingMode(data[0])
+	prec := uint(binary.BigEndian.Uint32(data[1:]))
+	if mode > big.ToPositiveInf || prec > MaxFloatPrec || len(data)-floatHeaderLen > maxFloatTextLen {
+		return nil, fmt.Errorf("%w: invalid big.Float data", def.ErrCanNotDecode)
+	}
+
+	// the text is parsed with the default rounding it was formatted for
+	x, ok := new(big.Float).SetPrec(prec).SetString(string(data[floatHeaderLen:]))
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid big.Float %q", def.ErrCanNotDecode, data[floatHeaderLen:])
+	}
+	if prec == 0 {
+		// SetString uses 64 bits for a precision of 0, which holds only 0 and Inf
+		x.SetPrec(0)
+	}
+	return x.SetMode(mode), nil
+}
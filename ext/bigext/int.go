@@ -0,0 +1,144 @@
+package bigext
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+
+	"github.com/shamaton/msgpack/v3/def"
+)
+
+var intFormat = &format[big.Int]{
+	code:       IntCode,
+	typ:        reflect.TypeOf(big.Int{}),
+	appendTo:   appendInt,
+	parse:      parseInt,
+	parsePlain: parsePlainInt,
+}
+
+// Coders of big.Int values.
+var (
+	IntEncoder       = &encoder[big.Int]{f: intFormat}
+	IntDecoder       = &decoder[big.Int]{f: intFormat}
+	IntStreamEncoder = &streamEncoder[big.Int]{f: intFormat}
+	IntStreamDecoder = &streamDecoder[big.Int]{f: intFormat}
+)
+
+func appendInt(b []byte, code int8, x *big.Int) ([]byte, error) {
+	if encodeIntAsPlain {
+		if x.IsUint64() {
+			return appendUint(b, x.Uint64()), nil
+		}
+		if x.IsInt64() {
+			return appendNegative(b, x.Int64()), nil
+		}
+	}
+	return appendExt(b, code, appendMagnitude([]byte{sign(x)}, x))
+}
+
+func parseInt(data []byte) (*big.Int, error) {
+	if len(data) == 0 || data[0] > 1 {
+		return nil, fmt.Errorf("%w: invalid big.Int data", def.ErrCanNotDecode)
+	}
+	x := new(big.Int).SetBytes(data[1:])
+	if data[0] == 1 {
+		x.Neg(x)
+	}
+	return x, nil
+}
+
+// sign returns the sign byte of the layouts, which is 1 when x is negative.
+func sign(x *big.Int) byte {
+	if x.Sign() < 0 {
+		return 1
+	}
+	return 0
+}
+
+func appendMagnitude(b []byte, x *big.Int) []byte {
+	n := len(b)
+	b = append(b, make([]byte, (x.BitLen()+7)/8)...)
+	x.FillBytes(b[n:])
+	return b
+}
+
+// appendUint appends v in the smallest unsigned integer format.
+func appendUint(b []byte, v uint64) []byte {
+	switch {
+	case v <= def.PositiveFixIntMax:
+		return append(b, byte(v))
+	case v <= math.MaxUint8:
+		return append(b, def.Uint8, byte(v))
+	case v <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, def.Uint16), uint16(v)) // #nosec G115 -- v is checked to fit.
+	case v <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(b, def.Uint32), uint32(v)) // #nosec G115 -- v is checked to fit.
+	}
+	return binary.BigEndian.AppendUint64(append(b, def.Uint64), v)
+}
+
+// appendNegative appends a negative v in the smallest signed integer format.
+func appendNegative(b []byte, v int64) []byte {
+	switch {
+	case v >= def.NegativeFixintMin:
+		return append(b, byte(v)) // #nosec G115 -- MessagePack encodes signed integers as two's-complement bytes.
+	case v >= math.MinInt8:
+		return append(b, def.Int8, byte(v)) // #nosec G115 -- MessagePack encodes signed integers as two's-complement bytes.
+	case v >= math.MinInt16:
+		return binary.BigEndian.AppendUint16(append(b, def.Int16), uint16(v)) // #nosec G115 -- MessagePack encodes signed integers as two's-complement bytes.
+	case v >= math.MinInt32:
+		return binary.BigEndian.AppendUint32(append(b, def.Int32), uint32(v)) // #nosec G115 -- MessagePack encodes signed integers as two's-complement bytes.
+	}
+	return binary.BigEndian.AppendUint64(append(b, def.Int64), uint64(v)) // #nosec G115 -- MessagePack encodes signed integers as two's-complement bytes.
+}
+
+// plainLen returns the number of bytes after code of a plain integer.
+func plainLen(code byte) (int, bool) {
+	switch code {
+	case def.Uint8, def.Int8:
+		return def.Byte1, true
+	case def.Uint16, def.Int16:
+		return def.Byte2, true
+	case def.Uint32, def.Int32:
+		return def.Byte4, true
+	case def.Uint64, def.Int64:
+		return def.Byte8, true
+	}
+	return 0, code <= def.PositiveFixIntMax || code >= 0xe0
+}
+
+// plainData returns the code and the following bytes of the plain integer
+// at offset, and the offset after it.
+func plainData(offset int, d []byte) (code byte, data []byte, end int, ok bool) {
+	if offset >= len(d) {
+		return 0, nil, 0, false
+	}
+	code = d[offset]
+	n, ok := plainLen(code)
+	if !ok || len(d)-offset-1 < n {
+		return 0, nil, 0, false
+	}
+	offset++
+	return code, d[offset : offset+n], offset + n, true
+}
+
+func parsePlainInt(code byte, data []byte) (*big.Int, error) {
+	n, ok := plainLen(code)
+	if !ok || len(data) != n {
+		return nil, fmt.Errorf("%w: code %x decoding big.Int", def.ErrCanNotDecode, code)
+	}
+	var u uint64
+	for _, c := range data {
+		u = u<<8 | uint64(c)
+	}
+	switch code {
+	case def.Uint8, def.Uint16, def.Uint32, def.Uint64:
+		return new(big.Int).SetUint64(u), nil
+	case def.Int8, def.Int16, def.Int32, def.Int64:
+		shift := 64 - 8*n
+		return big.NewInt(int64(u<<shift) >> shift), nil // #nosec G115 -- signed formats are two's-complement bytes.
+	}
+	return big.NewInt(int64(int8(code))), nil // #nosec G115 -- negative fixints are two's-complement bytes.
+}
@@ -0,0 +1,61 @@
+package bigext
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/shamaton/msgpack/v3/def"
+)
+
+var ratFormat = &format[big.Rat]{
+	code:     RatCode,
+	typ:      reflect.TypeOf(big.Rat{}),
+	appendTo: appendRat,
+	parse:    parseRat,
+}
+
+// Coders of big.Rat values.
+var (
+	RatEncoder       = &encoder[big.Rat]{f: ratFormat}
+	RatDecoder       = &decoder[big.Rat]{f: ratFormat}
+	RatStreamEncoder = &streamEncoder[big.Rat]{f: ratFormat}
+	RatStreamDecoder = &streamDecoder[big.Rat]{f: ratFormat}
+)
+
+const ratHeaderLen = 1 + 4
+
+func appendRat(b []byte, code int8, x *big.Rat) ([]byte, error) {
+	num, denom := x.Num(), x.Denom()
+	data := make([]byte, ratHeaderLen)
+	data[0] = sign(num)
+	data = appendMagnitude(data, num)
+	n := len(data) - ratHeaderLen
+	if uint64(n) > 1<<32-1 {
+		return nil, fmt.Errorf("%w: big.Rat numerator of %d bytes", def.ErrUnsupportedLength, n)
+	}
+	binary.BigEndian.PutUint32(data[1:], uint32(n)) // #nosec G115 -- n is checked to fit.
+	data = appendMagnitude(data, denom)
+	return appendExt(b, code, data)
+}
+
+func parseRat(data []byte) (*big.Rat, error) {
+	if len(data) < ratHeaderLen || data[0] > 1 {
+		return nil, fmt.Errorf("%w: invalid big.Rat data", def.ErrCanNotDecode)
+	}
+	n := uint64(binary.BigEndian.Uint32(data[1:]))
+	rest := data[ratHeaderLen:]
+	if uint64(len(rest)) < n {
+		return nil, fmt.Errorf("%w: invalid big.Rat data", def.ErrCanNotDecode)
+	}
+	num := new(big.Int).SetBytes(rest[:n])
+	denom := new(big.Int).SetBytes(rest[n:])
+	if denom.Sign() == 0 {
+		return nil, fmt.Errorf("%w: big.Rat with a zero denominator", def.ErrCanNotDecode)
+	}
+	if data[0] == 1 {
+		num.Neg(num)
+	}
+	return new(big.Rat).SetFrac(num, denom), nil
+}
@@ -1,6 +1,7 @@
 package ext
 
 import (
+	"fmt"
 	"reflect"
 
 	"github.com/shamaton/msgpack/v3/def"
@@ -22,6 +23,89 @@ type Decoder interface {
 	AsValue(offset int, k reflect.Kind, d *[]byte) (interface{}, int, error)
 }
 
+// DecoderV2 defines an interface for decoding values from bytes into
+// their destination, so that one extension type can be decoded into
+// several Go types, such as a pointer or a value, or a named type.
+type DecoderV2 interface {
+	// Code returns the unique code representing the decoder type.
+	Code() int8
+
+	// IsType checks if the data at the given offset matches the expected type.
+	// Returns true if the type matches, false otherwise.
+	IsType(offset int, d *[]byte) bool
+
+	// DecodeValue decodes the data at the given offset into rv, which is
+	// settable. rv is an empty interface when the destination has no
+	// other type. Returns the new offset, and an error if decoding fails.
+	// An error wrapping def.ErrExtTypeMismatch, returned without setting
+	// rv, lets other decoders or the usual decoding of structs be tried.
+	DecodeValue(offset int, rv reflect.Value, d *[]byte) (int, error)
+}
+
+// IntAcceptor is implemented by decoders that also decode plain
+// integers, such as those of big.Int. When AcceptsInts returns true,
+// IsType and the decoding method are called with plain integers too, but
+// only when decoding into a struct or into the type of the decoder.
+// Stream decoders then get an inner type of 0 and the bytes after the
+// code as data.
+type IntAcceptor interface {
+	AcceptsInts() bool
+}
+
+// AcceptsInts reports whether d implements IntAcceptor and accepts plain
+// integers.
+func AcceptsInts(d any) bool {
+	a, ok := d.(IntAcceptor)
+	return ok && a.AcceptsInts()
+}
+
+// AdaptDecoder returns d as a DecoderV2. Unless d implements DecoderV2,
+// the value that AsValue returns for the kind of the destination is set
+// to it when the destination can hold it.
+func AdaptDecoder(d Decoder) DecoderV2 {
+	if v2, ok := d.(DecoderV2); ok {
+		return v2
+	}
+	return &decoderAdapter{d}
+}
+
+type decoderAdapter struct {
+	Decoder
+}
+
+func (a *decoderAdapter) AcceptsInts() bool {
+	return AcceptsInts(a.Decoder)
+}
+
+func (a *decoderAdapter) DecodeValue(offset int, rv reflect.Value, d *[]byte) (int, error) {
+	v, offset, err := a.AsValue(offset, rv.Kind(), d)
+	if err != nil {
+		return 0, err
+	}
+	if err = setValue(rv, v); err != nil {
+		return 0, err
+	}
+	return offset, nil
+}
+
+// setValue sets v to rv, failing with def.ErrExtTypeMismatch when rv
+// can not hold it.
+func setValue(rv reflect.Value, v any) error {
+	x := reflect.ValueOf(v)
+	if !x.IsValid() {
+		if rv.Kind() != reflect.Interface {
+			return fmt.Errorf("%w: extension decoder returned nil for %v", def.ErrExtTypeMismatch, rv.Type())
+		}
+		rv.SetZero()
+		return nil
+	}
+	if !x.Type().AssignableTo(rv.Type()) {
+		return fmt.Errorf("%w: extension decoder returned %T for %v", def.ErrExtTypeMismatch, v, rv.Type())
+	}
+	rv.Set(x)
+	return nil
+}
+
 // DecoderCommon provides common utility methods for decoding data from bytes.
 type DecoderCommon struct{}
 
@@ -20,3 +20,48 @@ type StreamDecoder interface {
 	// Returns the decoded value or an error if the conversion fails.
 	ToValue(code byte, data []byte, k reflect.Kind) (any, error)
 }
+
+// StreamDecoderV2 defines an interface for decoding streams of data into
+// their destination, so that one extension type can be decoded into
+// several Go types, such as a pointer or a value, or a named type.
+type StreamDecoderV2 interface {
+	// Code returns the unique identifier for the decoder.
+	Code() int8
+
+	// IsType checks if the provided code, inner type, and data length match the expected type.
+	// Returns true if the type matches, otherwise false.
+	IsType(code byte, innerType int8, dataLength int) bool
+
+	// DecodeValue decodes the raw data into rv, which is settable. rv is
+	// an empty interface when the destination has no other type. data is
+	// only valid until DecodeValue returns. An error wrapping
+	// def.ErrExtTypeMismatch, returned without setting rv, lets other
+	// decoders be tried for structs.
+	DecodeValue(code byte, data []byte, rv reflect.Value) error
+}
+
+// AdaptStreamDecoder returns d as a StreamDecoderV2. Unless d implements
+// StreamDecoderV2, the value that ToValue returns for the kind of the
+// destination is set to it when the destination can hold it.
+func AdaptStreamDecoder(d StreamDecoder) StreamDecoderV2 {
+	if v2, ok := d.(StreamDecoderV2); ok {
+		return v2
+	}
+	return &streamDecoderAdapter{d}
+}
+
+type streamDecoderAdapter struct {
+	StreamDecoder
+}
+
+func (a *streamDecoderAdapter) AcceptsInts() bool {
+	return AcceptsInts(a.StreamDecoder)
+}
+
+func (a *streamDecoderAdapter) DecodeValue(code byte, data []byte, rv reflect.Value) error {
+	v, err := a.ToValue(code, data, rv.Kind())
+	if err != nil {
+		return err
+	}
+	return setValue(rv, v)
+}
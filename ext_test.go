@@ -0,0 +1,416 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shamaton/msgpack/v3"
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/ext"
+	tu "github.com/shamaton/msgpack/v3/internal/common/testutil"
+	extTime "github.com/shamaton/msgpack/v3/time"
+)
+
+type registeredPoint struct {
+	X, Y int32
+}
+
+type registeredLabel string
+
+var errRegisteredExt = errors.New("registered ext")
+
+func marshalPoint(p registeredPoint) ([]byte, error) {
+	b := binary.BigEndian.AppendUint32(nil, uint32(p.X))
+	return binary.BigEndian.AppendUint32(b, uint32(p.Y)), nil
+}
+
+func unmarshalPoint(b []byte) (registeredPoint, error) {
+	if len(b) != 8 {
+		return registeredPoint{}, errRegisteredExt
+	}
+	return registeredPoint{
+		X: int32(binary.BigEndian.Uint32(b)),
+		Y: int32(binary.BigEndian.Uint32(b[4:])),
+	}, nil
+}
+
+func marshalLabel(l registeredLabel) ([]byte, error) {
+	if l == "bad" {
+		return nil, errRegisteredExt
+	}
+	return []byte(l), nil
+}
+
+func unmarshalLabel(b []byte) (registeredLabel, error) {
+	return registeredLabel(b), nil
+}
+
+func TestRegisterExt(t *testing.T) {
+	tu.NoError(t, msgpack.RegisterExt(30, marshalPoint, unmarshalPoint))
+	tu.NoError(t, msgpack.RegisterExt(31, marshalLabel, unmarshalLabel))
+	defer func() {
+		tu.NoError(t, msgpack.UnregisterExt[registeredPoint](30))
+		tu.NoError(t, msgpack.UnregisterExt[registeredLabel](31))
+	}()
+
+	t.Run("Formats", func(t *testing.T) {
+		long := make([]byte, 300)
+		for i := range long {
+			long[i] = 'a'
+		}
+		tests := []struct {
+			name   string
+			v      any
+			header []byte
+		}{
+			{"Fixext8", registeredPoint{X: 1, Y: -1}, []byte{def.Fixext8, 30}},
+			{"Fixext1", registeredLabel("a"), []byte{def.Fixext1, 31}},
+			{"Fixext16", registeredLabel("0123456789abcdef"), []byte{def.Fixext16, 31}},
+			{"Ext8", registeredLabel("abc"), []byte{def.Ext8, 3, 31}},
+			{"Ext8Empty", registeredLabel(""), []byte{def.Ext8, 0, 31}},
+			{"Ext16", registeredLabel(long), []byte{def.Ext16, 1, 44, 31}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				for _, m := range marshallers {
+					b, err := m.m(tt.v)
+					tu.NoError(t, err)
+					tu.EqualSlice(t, b[:len(tt.header)], tt.header)
+
+					for _, u := range unmarshallers {
+						var v any
+						tu.NoError(t, u.u(b, &v))
+						tu.Equal(t, v, tt.v)
+					}
+				}
+			})
+		}
+	})
+
+	type st struct {
+		P      registeredPoint
+		PP     *registeredPoint
+		L      registeredLabel
+		Points []registeredPoint
+		Labels map[string]registeredLabel
+	}
+	v := st{
+		P:      registeredPoint{X: 1, Y: 2},
+		PP:     &registeredPoint{X: 3, Y: 4},
+		L:      "label",
+		Points: []registeredPoint{{X: 5}, {Y: 6}},
+		Labels: map[string]registeredLabel{"a": "b"},
+	}
+	encdec(t, encdecArg[st]{n: "Fields", v: v})
+
+	t.Run("MarshalError", func(t *testing.T) {
+		for _, m := range marshallers {
+			_, err := m.m([]registeredLabel{"ok", "bad"})
+			tu.IsError(t, err, errRegisteredExt)
+		}
+	})
+
+	t.Run("UnmarshalError", func(t *testing.T) {
+		b := []byte{def.Ext8, 3, 30, 1, 2, 3}
+		for _, u := range unmarshallers {
+			var p registeredPoint
+			err := u.u(b, &p)
+			tu.IsError(t, err, def.ErrCanNotDecode)
+			tu.IsError(t, err, errRegisteredExt)
+		}
+	})
+
+	t.Run("Unregistered", func(t *testing.T) {
+		tu.NoError(t, msgpack.UnregisterExt[registeredLabel](31))
+		defer func() {
+			tu.NoError(t, msgpack.RegisterExt(31, marshalLabel, unmarshalLabel))
+		}()
+		b := []byte{def.Fixext1, 31, 'a'}
+		for _, u := range unmarshallers {
+			var v any
+			tu.NoError(t, u.u(b, &v))
+			tu.Equal[any](t, v, msgpack.Ext{Type: 31, Data: []byte{'a'}})
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		err := msgpack.RegisterExt[registeredPoint](32, nil, unmarshalPoint)
+		tu.Error(t, err)
+	})
+
+	t.Run("Types", func(t *testing.T) {
+		// pointers and unnamed types are rejected
+		marshal := func(*registeredPoint) ([]byte, error) { return nil, nil }
+		unmarshal := func([]byte) (*registeredPoint, error) { return nil, nil }
+		tu.Error(t, msgpack.RegisterExt(33, marshal, unmarshal))
+		tu.Error(t, msgpack.RegisterExt(33, func(int) ([]byte, error) { return nil, nil }, func([]byte) (int, error) { return 0, nil }))
+	})
+
+	t.Run("MarshalOnce", func(t *testing.T) {
+		type counted registeredPoint
+		calls := 0
+		marshal := func(p counted) ([]byte, error) {
+			calls++
+			return marshalPoint(registeredPoint(p))
+		}
+		unmarshal := func(b []byte) (counted, error) {
+			p, err := unmarshalPoint(b)
+			return counted(p), err
+		}
+		tu.NoError(t, msgpack.RegisterExt(34, marshal, unmarshal))
+		defer func() {
+			tu.NoError(t, msgpack.UnregisterExt[counted](34))
+		}()
+
+		type st struct {
+			A   counted
+			Nil *counted
+			M   map[counted]counted
+		}
+		v := st{A: counted{X: 1}, M: map[counted]counted{{X: 2}: {Y: 3}, {X: 4}: {Y: 5}}}
+		options := []msgpack.EncodeOptions{{}, {StructAsArray: true}, {Canonical: true}}
+		for _, o := range options {
+			for _, m := range []marshaller{o.Marshal, func(v any) ([]byte, error) {
+				buf := bytes.Buffer{}
+				err := o.MarshalWrite(&buf, v)
+				return buf.Bytes(), err
+			}} {
+				calls = 0
+				b, err := m(v)
+				tu.NoError(t, err)
+				if !o.Canonical {
+					tu.Equal(t, calls, 5)
+				}
+				if o.StructAsArray {
+					continue
+				}
+				for _, u := range unmarshallers {
+					var r st
+					tu.NoError(t, u.u(b, &r))
+					tu.Equal(t, r.A, v.A)
+					tu.Equal(t, r.Nil == nil, true)
+					tu.Equal(t, len(r.M), 2)
+					tu.Equal(t, r.M[counted{X: 4}], counted{Y: 5})
+				}
+			}
+		}
+	})
+}
+
+type (
+	v2Point struct {
+		X, Y int32
+	}
+	v2OtherPoint v2Point
+)
+
+// v2PointCoder writes v2Point as a fixext8 and decodes it into
+// v2OtherPoint and interfaces too.
+type v2PointCoder struct{}
+
+func (v2PointCoder) Code() int8 {
+	return 40
+}
+
+func (v2PointCoder) Type() reflect.Type {
+	return reflect.TypeOf(v2Point{})
+}
+
+func (v2PointCoder) data(value reflect.Value) []byte {
+	b, _ := marshalPoint(registeredPoint(value.Interface().(v2Point)))
+	return append([]byte{def.Fixext8, 40}, b...)
+}
+
+func (v2PointCoder) decode(data []byte, rv reflect.Value) error {
+	p, err := unmarshalPoint(data)
+	if err != nil {
+		return err
+	}
+	switch rv.Type() {
+	case reflect.TypeOf(v2Point{}):
+		rv.Set(reflect.ValueOf(v2Point(p)))
+	case reflect.TypeOf(v2OtherPoint{}):
+		rv.Set(reflect.ValueOf(v2OtherPoint(p)))
+	default:
+		if rv.Kind() != reflect.Interface {
+			return fmt.Errorf("%w: %v", def.ErrExtTypeMismatch, rv.Type())
+		}
+		rv.Set(reflect.ValueOf(&v2Point{X: p.X, Y: p.Y}))
+	}
+	return nil
+}
+
+func (c v2PointCoder) CalcByteSize(value reflect.Value) (int, error) {
+	return len(c.data(value)), nil
+}
+
+func (c v2PointCoder) WriteToBytes(value reflect.Value, offset int, bytes *[]byte) int {
+	return offset + copy((*bytes)[offset:], c.data(value))
+}
+
+func (c v2PointCoder) Write(w ext.StreamWriter, value reflect.Value) error {
+	return w.WriteBytes(c.data(value))
+}
+
+func (v2PointCoder) IsType(offset int, d *[]byte) bool {
+	b := *d
+	return len(b) > offset+1 && b[offset] == def.Fixext8 && b[offset+1] == 40
+}
+
+func (c v2PointCoder) DecodeValue(offset int, rv reflect.Value, d *[]byte) (int, error) {
+	end := offset + 2 + 8
+	if len(*d) < end {
+		return 0, def.ErrTooShortBytes
+	}
+	return end, c.decode((*d)[offset+2:end], rv)
+}
+
+type v2PointStreamDecoder struct{ v2PointCoder }
+
+func (v2PointStreamDecoder) IsType(code byte, innerType int8, _ int) bool {
+	return code == def.Fixext8 && innerType == 40
+}
+
+func (c v2PointStreamDecoder) DecodeValue(_ byte, data []byte, rv reflect.Value) error {
+	return c.decode(data, rv)
+}
+
+func TestExtDecoderV2(t *testing.T) {
+	c := v2PointCoder{}
+	tu.NoError(t, msgpack.AddExtCoderV2(c, c))
+	tu.NoError(t, msgpack.AddExtStreamCoderV2(c, v2PointStreamDecoder{c}))
+	defer func() {
+		tu.NoError(t, msgpack.RemoveExtCoderV2(c, c))
+		tu.NoError(t, msgpack.RemoveExtStreamCoderV2(c, v2PointStreamDecoder{c}))
+	}()
+
+	type st struct {
+		A v2Point
+		B v2OtherPoint
+		C *v2OtherPoint
+		D any
+	}
+	o := msgpack.DecodeOptions{StructAsArray: true}
+	decoders := []func([]byte, any) error{
+		o.Unmarshal,
+		func(b []byte, v any) error { return o.UnmarshalRead(bytes.NewReader(b), v) },
+	}
+	for _, m := range marshallers {
+		p := v2Point{X: 1, Y: -2}
+		b, err := m.m([]v2Point{p, p, p, p})
+		tu.NoError(t, err)
+
+		for _, u := range decoders {
+			var v st
+			tu.NoError(t, u(b, &v))
+			tu.Equal(t, v.A, p)
+			tu.Equal(t, v.B, v2OtherPoint(p))
+			tu.Equal(t, *v.C, v2OtherPoint(p))
+			tu.Equal[any](t, v.D, &p)
+
+			// the decoder returns def.ErrExtTypeMismatch for other types
+			var items []typedItem
+			err = u(b, &items)
+			tu.IsError(t, err, def.ErrCanNotDecode)
+		}
+	}
+}
+
+func TestAdaptDecoder(t *testing.T) {
+	tm := time.Unix(10, 0).UTC()
+	b, err := msgpack.Marshal(tm)
+	tu.NoError(t, err)
+
+	d := ext.AdaptDecoder(extTime.Decoder)
+	sd := ext.AdaptStreamDecoder(extTime.StreamDecoder)
+	tu.Equal(t, d.IsType(0, &b), true)
+
+	var a any
+	rv := reflect.ValueOf(&a).Elem()
+	o, err := d.DecodeValue(0, rv, &b)
+	tu.NoError(t, err)
+	tu.Equal(t, o, len(b))
+	tu.Equal(t, a.(time.Time).Equal(tm), true)
+
+	a = nil
+	tu.NoError(t, sd.DecodeValue(b[0], b[2:], rv))
+	tu.Equal(t, a.(time.Time).Equal(tm), true)
+
+	var n int
+	rv = reflect.ValueOf(&n).Elem()
+	_, err = d.DecodeValue(0, rv, &b)
+	tu.IsError(t, err, def.ErrExtTypeMismatch)
+	tu.IsError(t, sd.DecodeValue(b[0], b[2:], rv), def.ErrExtTypeMismatch)
+	tu.Equal(t, n, 0)
+}
+
+type zeroCodeValue struct {
+	V int
+}
+
+// zeroCodeCoder is a decoder for ext code 0 that checks only the type
+// byte, which plain integers must not be offered to.
+type zeroCodeCoder struct{}
+
+func (zeroCodeCoder) Code() int8 {
+	return 0
+}
+
+func (zeroCodeCoder) Type() reflect.Type {
+	return reflect.TypeOf(zeroCodeValue{})
+}
+
+func (zeroCodeCoder) CalcByteSize(reflect.Value) (int, error) {
+	return 3, nil
+}
+
+func (zeroCodeCoder) WriteToBytes(value reflect.Value, offset int, bytes *[]byte) int {
+	return offset + copy((*bytes)[offset:], []byte{def.Fixext1, 0, byte(value.Field(0).Int())})
+}
+
+func (zeroCodeCoder) Write(w ext.StreamWriter, value reflect.Value) error {
+	return w.WriteBytes([]byte{def.Fixext1, 0, byte(value.Field(0).Int())})
+}
+
+func (zeroCodeCoder) IsType(offset int, d *[]byte) bool {
+	return len(*d) > offset+2 && (*d)[offset+1] == 0
+}
+
+func (zeroCodeCoder) AsValue(offset int, _ reflect.Kind, d *[]byte) (any, int, error) {
+	return zeroCodeValue{V: int((*d)[offset+2])}, offset + 3, nil
+}
+
+type zeroCodeStreamDecoder struct{ zeroCodeCoder }
+
+func (zeroCodeStreamDecoder) IsType(_ byte, innerType int8, _ int) bool {
+	return innerType == 0
+}
+
+func (zeroCodeStreamDecoder) ToValue(_ byte, data []byte, _ reflect.Kind) (any, error) {
+	return zeroCodeValue{V: int(data[0])}, nil
+}
+
+func TestExtPlainIntNotOffered(t *testing.T) {
+	c := zeroCodeCoder{}
+	tu.NoError(t, msgpack.AddExtCoder(c, c))
+	tu.NoError(t, msgpack.AddExtStreamCoder(c, zeroCodeStreamDecoder{c}))
+	defer func() {
+		tu.NoError(t, msgpack.RemoveExtCoder(c, c))
+		tu.NoError(t, msgpack.RemoveExtStreamCoder(c, zeroCodeStreamDecoder{c}))
+	}()
+
+	encdec(t, encdecArg[zeroCodeValue]{n: "Ext", v: zeroCodeValue{V: 7}})
+
+	// a uint8 0 looks like the extension to the decoder
+	b := []byte{def.Uint8, 0, 0}
+	for _, u := range unmarshallers {
+		var v zeroCodeValue
+		tu.IsError(t, u.u(b[:2], &v), def.ErrCanNotDecode)
+		tu.IsError(t, u.u(b, &v), msgpack.Error)
+	}
+}
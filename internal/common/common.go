@@ -15,6 +15,8 @@ type FieldInfo struct {
 	Omit      bool    // omitempty flag
 	Tagged    bool    // tag name explicitly set
 	OmitPaths [][]int // paths to embedded fields with omitempty
+	// Formats are set by "time=" and "duration=" tag options
+	Formats FormatTags
 }
 
 // CollectFields collects all fields from a struct, expanding embedded structs
@@ -79,6 +81,7 @@ func (c *Common) collectFields(t reflect.Type, path []int, omitPaths [][]int) []
 			Omit:      omit,
 			Tagged:    tagged,
 			OmitPaths: omitPaths,
+			Formats:   formatTags(tag),
 		})
 	}
 
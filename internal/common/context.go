@@ -0,0 +1,113 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+type readDeadliner interface {
+	SetReadDeadline(t time.Time) error
+}
+
+type writeDeadliner interface {
+	SetWriteDeadline(t time.Time) error
+}
+
+// ReadDeadline applies the deadline of ctx to v when it supports
+// SetReadDeadline, and interrupts a pending read once ctx is done.
+// The returned function restores v and must be called after reading.
+// See watchDeadline for what happens to a deadline set on v beforehand.
+func ReadDeadline(ctx context.Context, v any) func() {
+	if d, ok := v.(readDeadliner); ok {
+		return watchDeadline(ctx, d.SetReadDeadline)
+	}
+	return func() {}
+}
+
+// WriteDeadline applies the deadline of ctx to v when it supports
+// SetWriteDeadline, and interrupts a pending write once ctx is done.
+// The returned function restores v and must be called after writing.
+// See watchDeadline for what happens to a deadline set on v beforehand.
+func WriteDeadline(ctx context.Context, v any) func() {
+	if d, ok := v.(writeDeadliner); ok {
+		return watchDeadline(ctx, d.SetWriteDeadline)
+	}
+	return func() {}
+}
+
+// watchDeadline leaves the deadline alone for a ctx that has no deadline
+// and is never done. Otherwise the deadline of ctx, or one in the past
+// once ctx is done, replaces the deadline set beforehand, which can't be
+// read back, and the deadline is cleared by the returned function.
+func watchDeadline(ctx context.Context, set func(time.Time) error) func() {
+	t, hasDeadline := ctx.Deadline()
+	if !hasDeadline && ctx.Done() == nil {
+		return func() {}
+	}
+	var mu sync.Mutex
+	restored, changed := false, hasDeadline
+	if hasDeadline {
+		_ = set(t)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !restored {
+			// a time in the past makes pending and later calls fail at once
+			_ = set(time.Unix(1, 0))
+			changed = true
+		}
+	})
+	return func() {
+		stop()
+		mu.Lock()
+		defer mu.Unlock()
+		restored = true
+		if changed {
+			_ = set(time.Time{})
+		}
+	}
+}
+
+// ContextErr returns the error of ctx when done is closed.
+// A nil done never reports an error.
+func ContextErr(ctx context.Context, done <-chan struct{}) error {
+	if done == nil {
+		return nil
+	}
+	select {
+	case <-done:
+		return ctx.Err()
+	default:
+		return nil
+	}
+}
+
+// DeadlineErr returns the error of ctx when err is a timeout and ctx is
+// done or its deadline has passed, and err otherwise. A deadline applied
+// by ReadDeadline or WriteDeadline can fail a call with a timeout just
+// before ctx reports that it is done.
+func DeadlineErr(ctx context.Context, err error) error {
+	if !isTimeout(err) {
+		return err
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if t, ok := ctx.Deadline(); ok && !time.Now().Before(t) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+func isTimeout(err error) bool {
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
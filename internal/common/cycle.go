@@ -0,0 +1,59 @@
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"unsafe"
+
+	"github.com/shamaton/msgpack/v3/def"
+)
+
+// Cycles detects pointer cycles while encoding. Values nested up to its
+// depth are not tracked, so acyclic data pays no cost until it gets deep.
+type Cycles struct {
+	level int
+	after int
+	seen  map[cycleKey]struct{}
+}
+
+// cycleKey identifies a pointer, map or slice. Slices that share the
+// backing array but differ in length are different values.
+type cycleKey struct {
+	ptr unsafe.Pointer
+	len int
+}
+
+func newCycleKey(rv reflect.Value) cycleKey {
+	k := cycleKey{ptr: rv.UnsafePointer()}
+	if rv.Kind() == reflect.Slice {
+		k.len = rv.Len()
+	}
+	return k
+}
+
+// Enter is called before the contents of the non-nil pointer, map or
+// slice rv are encoded. It fails with def.ErrCycle when rv is already
+// being encoded further up.
+func (c *Cycles) Enter(rv reflect.Value) error {
+	c.level++
+	if c.level <= c.after {
+		return nil
+	}
+	k := newCycleKey(rv)
+	if _, ok := c.seen[k]; ok {
+		return fmt.Errorf("%w via %v", def.ErrCycle, rv.Type())
+	}
+	if c.seen == nil {
+		c.seen = map[cycleKey]struct{}{}
+	}
+	c.seen[k] = struct{}{}
+	return nil
+}
+
+// Leave is called after the contents of rv are encoded.
+func (c *Cycles) Leave(rv reflect.Value) {
+	if c.level > c.after {
+		delete(c.seen, newCycleKey(rv))
+	}
+	c.level--
+}
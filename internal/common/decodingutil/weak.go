@@ -0,0 +1,67 @@
+package decodingutil
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/shamaton/msgpack/v3/def"
+)
+
+func numberError(s string, k reflect.Kind) error {
+	return fmt.Errorf("%w %q decoding as %v", def.ErrCanNotDecode, s, k)
+}
+
+// ParseInt parses the base 10 number in s. Whole-number floats such as
+// "3.0" are accepted.
+func ParseInt(s string, k reflect.Kind) (int64, error) {
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return v, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f != math.Trunc(f) {
+		return 0, numberError(s, k)
+	}
+	return Int64FromFloat64(f, k)
+}
+
+// ParseUint parses the base 10 number in s. Whole-number floats such as
+// "3.0" are accepted.
+func ParseUint(s string, k reflect.Kind) (uint64, error) {
+	if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return v, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f != math.Trunc(f) {
+		return 0, numberError(s, k)
+	}
+	if f < 0 || f >= 1<<64 {
+		return 0, RangeError(s, k)
+	}
+	return uint64(f), nil
+}
+
+// ParseFloat parses the number in s with the precision of k.
+func ParseFloat(s string, k reflect.Kind) (float64, error) {
+	bitSize := 64
+	if k == reflect.Float32 {
+		bitSize = 32
+	}
+	v, err := strconv.ParseFloat(s, bitSize)
+	if err != nil {
+		return 0, numberError(s, k)
+	}
+	return v, nil
+}
+
+// BoolFromInt64 converts 0 and 1 to a bool.
+func BoolFromInt64(v int64, k reflect.Kind) (bool, error) {
+	switch v {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	}
+	return false, RangeError(v, k)
+}
@@ -0,0 +1,99 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/shamaton/msgpack/v3/def"
+)
+
+// DurationType is the type that DurationFormat applies to.
+var DurationType = reflect.TypeOf(time.Duration(0))
+
+// DurationFormat selects how time.Duration values are represented.
+type DurationFormat int
+
+const (
+	// DurationFormatNanos is an integer of nanoseconds.
+	DurationFormatNanos DurationFormat = iota
+	// DurationFormatSeconds is an integer of seconds.
+	DurationFormatSeconds
+	// DurationFormatMilli is an integer of milliseconds.
+	DurationFormatMilli
+	// DurationFormatFloat is a float of seconds.
+	DurationFormatFloat
+	// DurationFormatString is a string formatted by time.Duration.String.
+	DurationFormatString
+)
+
+var durationFormatNames = map[string]DurationFormat{
+	"ns":     DurationFormatNanos,
+	"s":      DurationFormatSeconds,
+	"ms":     DurationFormatMilli,
+	"float":  DurationFormatFloat,
+	"string": DurationFormatString,
+}
+
+// durationFormatOf returns the format of a "duration=" tag option, such
+// as "duration=ms". Unknown names are ignored.
+func durationFormatOf(option string) (DurationFormat, bool) {
+	name, ok := strings.CutPrefix(option, "duration=")
+	if !ok {
+		return 0, false
+	}
+	f, ok := durationFormatNames[name]
+	return f, ok
+}
+
+// DurationValue returns d in the format f. Seconds and milliseconds are
+// truncated.
+func DurationValue(d time.Duration, f DurationFormat) reflect.Value {
+	switch f {
+	case DurationFormatSeconds:
+		return reflect.ValueOf(int64(d / time.Second))
+	case DurationFormatMilli:
+		return reflect.ValueOf(d.Milliseconds())
+	case DurationFormatFloat:
+		return reflect.ValueOf(d.Seconds())
+	case DurationFormatString:
+		return reflect.ValueOf(d.String())
+	}
+	return reflect.ValueOf(int64(d))
+}
+
+// DurationFromInt returns the duration of an integer in the format f,
+// which is nanoseconds for DurationFormatNanos.
+func DurationFromInt(v int64, f DurationFormat) (time.Duration, error) {
+	unit := time.Duration(1)
+	switch f {
+	case DurationFormatSeconds, DurationFormatFloat, DurationFormatString:
+		unit = time.Second
+	case DurationFormatMilli:
+		unit = time.Millisecond
+	}
+	if v > int64(math.MaxInt64/unit) || v < int64(math.MinInt64/unit) {
+		return 0, fmt.Errorf("%w %d decoding as %v", def.ErrValueOutOfRange, v, DurationType)
+	}
+	return time.Duration(v) * unit, nil
+}
+
+// DurationFromFloat returns the duration of a float of seconds.
+func DurationFromFloat(v float64) (time.Duration, error) {
+	ns := math.Round(v * float64(time.Second))
+	if math.IsNaN(ns) || ns < math.MinInt64 || ns >= math.MaxInt64 {
+		return 0, fmt.Errorf("%w %v decoding as %v", def.ErrValueOutOfRange, v, DurationType)
+	}
+	return time.Duration(ns), nil
+}
+
+// DurationFromString parses a string such as "1h30m".
+func DurationFromString(v string) (time.Duration, error) {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", def.ErrCanNotDecode, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,21 @@
+package encodingutil
+
+import "sort"
+
+// CanonicalStringOrder returns the indexes of names in the order their
+// MessagePack encodings compare bytewise. A string header grows with the
+// string length, so this is shortest first and then lexicographic.
+func CanonicalStringOrder(names []string) []int {
+	order := make([]int, len(names))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := names[order[i]], names[order[j]]
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+	return order
+}
@@ -0,0 +1,34 @@
+package encodingutil
+
+import "math"
+
+// CanonicalNaN32 is the only NaN bit pattern written in canonical mode.
+const CanonicalNaN32 = 0x7fc00000
+
+// IsFloat32Exact reports whether v survives a round trip through float32.
+// NaN never compares equal, so it is always reported as false.
+func IsFloat32Exact(v float64) bool {
+	return float64(float32(v)) == v
+}
+
+// Float32Bits returns the float32 bits of v. When canonical is set,
+// every NaN is folded into CanonicalNaN32.
+func Float32Bits(v float64, canonical bool) uint32 {
+	if canonical && math.IsNaN(v) {
+		return CanonicalNaN32
+	}
+	return math.Float32bits(float32(v))
+}
+
+// FloatAsInt returns v as int64 when it is a whole number that converts
+// back without loss. Negative zero is not converted because int format
+// drops the sign.
+func FloatAsInt(v float64) (int64, bool) {
+	if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+		return 0, false
+	}
+	if v == 0 && math.Signbit(v) {
+		return 0, false
+	}
+	return int64(v), true
+}
@@ -0,0 +1,44 @@
+package encodingutil
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/shamaton/msgpack/v3/def"
+)
+
+// IntFormat returns the format code and the payload size in bytes of
+// the int format that matches the width of k.
+func IntFormat(k reflect.Kind) (byte, int) {
+	switch k {
+	case reflect.Int8:
+		return def.Int8, def.Byte1
+	case reflect.Int16:
+		return def.Int16, def.Byte2
+	case reflect.Int32:
+		return def.Int32, def.Byte4
+	case reflect.Int:
+		if strconv.IntSize == 32 {
+			return def.Int32, def.Byte4
+		}
+	}
+	return def.Int64, def.Byte8
+}
+
+// UintFormat returns the format code and the payload size in bytes of
+// the uint format that matches the width of k.
+func UintFormat(k reflect.Kind) (byte, int) {
+	switch k {
+	case reflect.Uint8:
+		return def.Uint8, def.Byte1
+	case reflect.Uint16:
+		return def.Uint16, def.Byte2
+	case reflect.Uint32:
+		return def.Uint32, def.Byte4
+	case reflect.Uint:
+		if strconv.IntSize == 32 {
+			return def.Uint32, def.Byte4
+		}
+	}
+	return def.Uint64, def.Byte8
+}
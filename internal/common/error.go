@@ -0,0 +1,110 @@
+package common
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/shamaton/msgpack/v3/def"
+)
+
+// DecodeError returns err as a *def.DecodeError recording the offset,
+// code and type of the value that failed. An err that already is one is
+// returned as is, so the innermost value is reported.
+func DecodeError(err error, offset int, code byte, t reflect.Type) error {
+	if _, ok := err.(*def.DecodeError); ok {
+		return err
+	}
+	return &def.DecodeError{Offset: offset, Code: code, Type: t, Err: err}
+}
+
+// EncodeError returns err as a *def.EncodeError recording the type of
+// rv, or of the value it holds when rv is an interface. An err that
+// already is one is returned as is, so the innermost value is reported.
+func EncodeError(err error, rv reflect.Value) error {
+	if _, ok := err.(*def.EncodeError); ok {
+		return err
+	}
+	for rv.Kind() == reflect.Interface && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	var t reflect.Type
+	if rv.IsValid() {
+		t = rv.Type()
+	}
+	return &def.EncodeError{Type: t, Err: err}
+}
+
+// ErrorPath prepends elem to the path of err when it is a
+// *def.DecodeError or *def.EncodeError.
+func ErrorPath(err error, elem string) error {
+	switch e := err.(type) {
+	case *def.DecodeError:
+		e.PrependPath(elem)
+	case *def.EncodeError:
+		e.PrependPath(elem)
+	}
+	return err
+}
+
+// RootPath prepends the name of t to the path of err and joins its
+// elements. Unnamed and predeclared types, and a nil t, leave the path
+// relative.
+func RootPath(err error, t reflect.Type) error {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	root := ""
+	if t != nil && t.Name() != "" && t.PkgPath() != "" {
+		root = t.Name()
+	}
+
+	var path *string
+	switch e := err.(type) {
+	case *def.DecodeError:
+		e.ResolvePath(root)
+		path = &e.Path
+	case *def.EncodeError:
+		e.ResolvePath(root)
+		path = &e.Path
+	default:
+		return err
+	}
+	if root == "" && len(*path) > 0 && (*path)[0] == '.' {
+		*path = (*path)[1:]
+	}
+	return err
+}
+
+// IndexPath returns the path element of the i-th element of an array.
+func IndexPath(i int) string {
+	return "[" + strconv.Itoa(i) + "]"
+}
+
+// KeyPath returns the path element of a map value.
+func KeyPath(key reflect.Value) string {
+	if key.Kind() == reflect.Interface {
+		key = key.Elem()
+	}
+	if key.Kind() == reflect.String {
+		return "[" + strconv.Quote(key.String()) + "]"
+	}
+	if !key.IsValid() {
+		return "[nil]"
+	}
+	return fmt.Sprintf("[%v]", key.Interface())
+}
+
+// FieldPath returns the path element of the field of struct type t
+// reached by index.
+func FieldPath(t reflect.Type, index []int) string {
+	var f reflect.StructField
+	for _, i := range index {
+		if t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		f = t.Field(i)
+		t = f.Type
+	}
+	return "." + f.Name
+}
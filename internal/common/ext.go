@@ -0,0 +1,120 @@
+package common
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/shamaton/msgpack/v3/def"
+)
+
+// Ext is an extension value as it is encoded, used for the extension
+// types that have no registered decoder.
+type Ext struct {
+	Type int8
+	Data []byte
+}
+
+// ExtType is the type of Ext.
+var ExtType = reflect.TypeOf(Ext{})
+
+// ExtBytesEncoder is implemented by extension encoders that return the
+// whole encoded value at once. The byte encoder calls EncodeExt once for
+// each value while it calculates the size and writes the bytes it kept.
+type ExtBytesEncoder interface {
+	EncodeExt(value reflect.Value) ([]byte, error)
+}
+
+// IsExtType reports whether every value of t is handled by the extension
+// coders of t: t is a named type declared in a package, other than a
+// struct, so predeclared types such as int and unnamed ones such as
+// []byte keep their usual encoding.
+func IsExtType(t reflect.Type) bool {
+	return t.Kind() != reflect.Struct && t.Name() != "" && t.PkgPath() != ""
+}
+
+// IsExtCode reports whether code is the code of an extension.
+func IsExtCode(code byte) bool {
+	switch code {
+	case def.Fixext1, def.Fixext2, def.Fixext4, def.Fixext8, def.Fixext16, def.Ext8, def.Ext16, def.Ext32:
+		return true
+	}
+	return false
+}
+
+// ExtHeader appends the header of an extension of type typ with n bytes
+// of data to b, in the smallest of the fixext and ext formats.
+func ExtHeader(b []byte, typ int8, n int) ([]byte, error) {
+	switch {
+	case n == 1:
+		b = append(b, def.Fixext1)
+	case n == 2:
+		b = append(b, def.Fixext2)
+	case n == 4:
+		b = append(b, def.Fixext4)
+	case n == 8:
+		b = append(b, def.Fixext8)
+	case n == 16:
+		b = append(b, def.Fixext16)
+	case n <= math.MaxUint8:
+		b = append(b, def.Ext8, byte(n))
+	case n <= math.MaxUint16:
+		b = append(b, def.Ext16, byte(n>>8), byte(n))
+	case uint64(n) <= math.MaxUint32:
+		b = append(b, def.Ext32, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	default:
+		return nil, fmt.Errorf("extension data length %d is %w", n, def.ErrUnsupportedLength)
+	}
+	return append(b, byte(typ)), nil // #nosec G115 -- the type byte is a signed extension type.
+}
+
+// ExtData returns the type and data of the extension at offset in d, and
+// the offset after it. ok is false when the value is not an extension or
+// d ends inside it.
+func ExtData(offset int, d []byte) (typ int8, data []byte, end int, ok bool) {
+	if offset >= len(d) {
+		return 0, nil, 0, false
+	}
+	code := d[offset]
+	offset += def.Byte1
+	n := 0
+	switch code {
+	case def.Fixext1:
+		n = def.Byte1
+	case def.Fixext2:
+		n = def.Byte2
+	case def.Fixext4:
+		n = def.Byte4
+	case def.Fixext8:
+		n = def.Byte8
+	case def.Fixext16:
+		n = def.Byte16
+	case def.Ext8:
+		if len(d) < offset+def.Byte1 {
+			return 0, nil, 0, false
+		}
+		n = int(d[offset])
+		offset += def.Byte1
+	case def.Ext16:
+		if len(d) < offset+def.Byte2 {
+			return 0, nil, 0, false
+		}
+		n = int(binary.BigEndian.Uint16(d[offset:]))
+		offset += def.Byte2
+	case def.Ext32:
+		if len(d) < offset+def.Byte4 {
+			return 0, nil, 0, false
+		}
+		n = int(binary.BigEndian.Uint32(d[offset:]))
+		offset += def.Byte4
+	default:
+		return 0, nil, 0, false
+	}
+	if len(d)-offset-def.Byte1 < n {
+		return 0, nil, 0, false
+	}
+	typ = int8(d[offset]) // #nosec G115 -- the type byte is a signed extension type.
+	offset += def.Byte1
+	return typ, d[offset : offset+n], offset + n, true
+}
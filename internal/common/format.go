@@ -0,0 +1,57 @@
+package common
+
+import "strings"
+
+// Formats holds the representations of time.Time and time.Duration values.
+type Formats struct {
+	Time     TimeFormat
+	Duration DurationFormat
+}
+
+// FormatTags holds the formats set by the tag options of a field.
+type FormatTags struct {
+	Time        TimeFormat
+	Duration    DurationFormat
+	HasTime     bool
+	HasDuration bool
+}
+
+// Apply returns f with the formats set by t.
+func (t FormatTags) Apply(f Formats) Formats {
+	if t.HasTime {
+		f.Time = t.Time
+	}
+	if t.HasDuration {
+		f.Duration = t.Duration
+	}
+	return f
+}
+
+func formatTags(tag string) FormatTags {
+	var t FormatTags
+	parts := strings.Split(tag, ",")
+	for _, part := range parts[1:] {
+		if v, found := timeFormatOf(part); found {
+			t.Time, t.HasTime = v, true
+		}
+		if v, found := durationFormatOf(part); found {
+			t.Duration, t.HasDuration = v, true
+		}
+	}
+	return t
+}
+
+// FieldFormats maps the index of each field with format tags to them.
+// It is nil when no field has one.
+func FieldFormats(fields []FieldInfo) map[int]FormatTags {
+	var m map[int]FormatTags
+	for i, f := range fields {
+		if f.Formats.HasTime || f.Formats.HasDuration {
+			if m == nil {
+				m = map[int]FormatTags{}
+			}
+			m[i] = f.Formats
+		}
+	}
+	return m
+}
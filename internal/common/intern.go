@@ -0,0 +1,50 @@
+package common
+
+import "sync"
+
+// InternMaxLen is the longest string in bytes that an Interner keeps.
+const InternMaxLen = 64
+
+// Interner shares one allocation between equal decoded strings.
+// It holds at most a fixed number of strings and is safe for concurrent use.
+type Interner struct {
+	mu      sync.Mutex
+	strs    map[string]string
+	maxSize int
+}
+
+// NewInterner returns an Interner that holds at most maxSize strings.
+func NewInterner(maxSize int) *Interner {
+	return &Interner{
+		strs:    make(map[string]string),
+		maxSize: maxSize,
+	}
+}
+
+// String returns the interned string equal to bs. Once the table is full,
+// strings that are not in it yet are returned as new allocations.
+func (i *Interner) String(bs []byte) string {
+	if len(bs) > InternMaxLen {
+		return string(bs)
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// the conversion in the map index expression does not allocate
+	if s, ok := i.strs[string(bs)]; ok {
+		return s
+	}
+	s := string(bs)
+	if len(i.strs) < i.maxSize {
+		i.strs[s] = s
+	}
+	return s
+}
+
+// Len returns the number of interned strings.
+func (i *Interner) Len() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return len(i.strs)
+}
@@ -0,0 +1,50 @@
+package common
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"unsafe"
+
+	tu "github.com/shamaton/msgpack/v3/internal/common/testutil"
+)
+
+func TestInterner(t *testing.T) {
+	t.Run("Shared", func(t *testing.T) {
+		in := NewInterner(10)
+		a := in.String([]byte("key"))
+		b := in.String([]byte("key"))
+		tu.Equal(t, a, "key")
+		tu.Equal(t, unsafe.StringData(a), unsafe.StringData(b))
+		tu.Equal(t, in.Len(), 1)
+	})
+	t.Run("Bounded", func(t *testing.T) {
+		in := NewInterner(2)
+		in.String([]byte("a"))
+		in.String([]byte("b"))
+		c := in.String([]byte("c"))
+		tu.Equal(t, c, "c")
+		tu.Equal(t, in.Len(), 2)
+	})
+	t.Run("Long", func(t *testing.T) {
+		in := NewInterner(10)
+		s := strings.Repeat("x", InternMaxLen+1)
+		tu.Equal(t, in.String([]byte(s)), s)
+		tu.Equal(t, in.Len(), 0)
+	})
+	t.Run("Concurrent", func(t *testing.T) {
+		in := NewInterner(100)
+		wg := sync.WaitGroup{}
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 100; j++ {
+					in.String([]byte{byte('a' + j%26)})
+				}
+			}()
+		}
+		wg.Wait()
+		tu.Equal(t, in.Len(), 26)
+	})
+}
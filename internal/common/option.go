@@ -0,0 +1,110 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/shamaton/msgpack/v3/def"
+)
+
+// EncodeOption holds the settings shared by the byte and stream encoders.
+// The zero value is the default behavior.
+type EncodeOption struct {
+	AsArray        bool
+	Canonical      bool
+	CompactFloats  bool
+	FloatAsInt     bool
+	FixedWidthInts bool
+	CycleDepth     int
+	TimeFormat     TimeFormat
+	DurationFormat DurationFormat
+}
+
+// DefaultCycleDepth is the nesting of pointers, maps and slices after
+// which the encoders start tracking them to detect cycles.
+const DefaultCycleDepth = 1000
+
+// Cycles returns a Cycles that starts tracking at the depth of opt.
+func (opt EncodeOption) Cycles() Cycles {
+	switch {
+	case opt.CycleDepth > 0:
+		return Cycles{after: opt.CycleDepth}
+	case opt.CycleDepth < 0:
+		return Cycles{}
+	}
+	return Cycles{after: DefaultCycleDepth}
+}
+
+// DefaultMaxDepth is the nesting depth limit used when none is set.
+const DefaultMaxDepth = 10000
+
+// DecodeOption holds the settings shared by the byte and stream decoders.
+// The zero value is the default behavior.
+type DecodeOption struct {
+	AsArray  bool
+	ZeroCopy bool
+	Interner *Interner
+	MaxDepth int
+	Limits   Limits
+
+	ContinueOnError bool
+	WeakTypes       bool
+	Narrowing       Narrowing
+	TimeFormat      TimeFormat
+	DurationFormat  DurationFormat
+}
+
+// FixedTypes reports whether slices and maps of basic types may be
+// decoded in bulk, which applies neither weak types nor narrowing.
+func (opt DecodeOption) FixedTypes() bool {
+	return !opt.WeakTypes && opt.Narrowing.Default()
+}
+
+// Depth returns the nesting depth limit of opt.
+func (opt DecodeOption) Depth() int {
+	if opt.MaxDepth > 0 {
+		return opt.MaxDepth
+	}
+	return DefaultMaxDepth
+}
+
+// Limits holds the sizes that the decoders check before allocating.
+// Zero means no limit.
+type Limits struct {
+	MaxStringLen  int
+	MaxBinLen     int
+	MaxArrayLen   int
+	MaxMapLen     int
+	MaxTotalBytes int
+}
+
+// CheckStringLen fails when a string of n bytes exceeds the limit.
+func (l Limits) CheckStringLen(n int) error {
+	return checkLimit(n, l.MaxStringLen, def.ErrMaxStringLenExceeded)
+}
+
+// CheckBinLen fails when a bin of n bytes exceeds the limit.
+func (l Limits) CheckBinLen(n int) error {
+	return checkLimit(n, l.MaxBinLen, def.ErrMaxBinLenExceeded)
+}
+
+// CheckArrayLen fails when an array of n elements exceeds the limit.
+func (l Limits) CheckArrayLen(n int) error {
+	return checkLimit(n, l.MaxArrayLen, def.ErrMaxArrayLenExceeded)
+}
+
+// CheckMapLen fails when a map of n pairs exceeds the limit.
+func (l Limits) CheckMapLen(n int) error {
+	return checkLimit(n, l.MaxMapLen, def.ErrMaxMapLenExceeded)
+}
+
+// CheckTotalBytes fails when n bytes of input exceed the limit.
+func (l Limits) CheckTotalBytes(n int) error {
+	return checkLimit(n, l.MaxTotalBytes, def.ErrMaxTotalBytesExceeded)
+}
+
+func checkLimit(n, limit int, err error) error {
+	if limit > 0 && n > limit {
+		return fmt.Errorf("%w: %d > %d", err, n, limit)
+	}
+	return nil
+}
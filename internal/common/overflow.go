@@ -0,0 +1,168 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common/decodingutil"
+)
+
+// IntOverflow selects what happens when an integer does not fit the
+// integer kind it is decoded as.
+type IntOverflow int
+
+const (
+	// IntOverflowError fails with def.ErrValueOutOfRange.
+	IntOverflowError IntOverflow = iota
+	// IntOverflowSaturate clamps the value to the nearest bound of the kind.
+	IntOverflowSaturate
+	// IntOverflowWrap keeps the low bits like a Go conversion does.
+	IntOverflowWrap
+)
+
+// FloatToInt selects what happens when a float is decoded as an integer.
+type FloatToInt int
+
+const (
+	// FloatToIntTruncate drops the fractional part.
+	FloatToIntTruncate FloatToInt = iota
+	// FloatToIntError fails with def.ErrFloatTruncated unless the float
+	// is a whole number.
+	FloatToIntError
+)
+
+// Narrowing applies the overflow policies to integers and floats that
+// are decoded as integer kinds. The zero value fails on integer overflow
+// and truncates floats.
+type Narrowing struct {
+	IntOverflow IntOverflow
+	FloatToInt  FloatToInt
+}
+
+// Default reports whether n is the zero value.
+func (n Narrowing) Default() bool {
+	return n == Narrowing{}
+}
+
+// Int fits v into the signed kind k.
+func (n Narrowing) Int(v int64, k reflect.Kind) (int64, error) {
+	switch n.IntOverflow {
+	case IntOverflowSaturate:
+		lo, hi := intBounds(k)
+		return min(max(v, lo), hi), nil
+	case IntOverflowWrap:
+		switch k {
+		case reflect.Int8:
+			return int64(int8(v)), nil // #nosec G115 -- wrapping is requested.
+		case reflect.Int16:
+			return int64(int16(v)), nil // #nosec G115 -- wrapping is requested.
+		case reflect.Int32:
+			return int64(int32(v)), nil // #nosec G115 -- wrapping is requested.
+		case reflect.Int:
+			return int64(int(v)), nil
+		}
+	}
+	return decodingutil.IntValueForKind(v, k)
+}
+
+// Uint fits v into the unsigned kind k.
+func (n Narrowing) Uint(v uint64, k reflect.Kind) (uint64, error) {
+	switch n.IntOverflow {
+	case IntOverflowSaturate:
+		return min(v, uintBound(k)), nil
+	case IntOverflowWrap:
+		switch k {
+		case reflect.Uint8:
+			return uint64(uint8(v)), nil // #nosec G115 -- wrapping is requested.
+		case reflect.Uint16:
+			return uint64(uint16(v)), nil // #nosec G115 -- wrapping is requested.
+		case reflect.Uint32:
+			return uint64(uint32(v)), nil // #nosec G115 -- wrapping is requested.
+		case reflect.Uint:
+			return uint64(uint(v)), nil // #nosec G115 -- wrapping is requested.
+		}
+	}
+	return decodingutil.UintValueForKind(v, k)
+}
+
+// IntFromUint converts an unsigned wire value for the signed kind k.
+// The result still has to be fitted with Int.
+func (n Narrowing) IntFromUint(v uint64, k reflect.Kind) (int64, error) {
+	if v <= math.MaxInt64 {
+		return int64(v), nil
+	}
+	switch n.IntOverflow {
+	case IntOverflowSaturate:
+		return math.MaxInt64, nil
+	case IntOverflowWrap:
+		return int64(v), nil // #nosec G115 -- wrapping is requested.
+	}
+	return decodingutil.Int64FromUint64(v, k)
+}
+
+// UintFromInt converts a signed wire value for the unsigned kind k.
+// The result still has to be fitted with Uint.
+func (n Narrowing) UintFromInt(v int64, k reflect.Kind) (uint64, error) {
+	if v >= 0 {
+		return uint64(v), nil
+	}
+	switch n.IntOverflow {
+	case IntOverflowSaturate:
+		return 0, nil
+	case IntOverflowWrap:
+		return uint64(v), nil // #nosec G115 -- wrapping is requested.
+	}
+	return decodingutil.Uint64FromInt64(v, k)
+}
+
+// IntFromFloat converts a float for the signed kind k. The result still
+// has to be fitted with Int. Floats outside of int64 only saturate, as
+// they have no low bits to wrap, and NaN always fails.
+func (n Narrowing) IntFromFloat(v float64, k reflect.Kind) (int64, error) {
+	if n.FloatToInt == FloatToIntError && v != math.Trunc(v) && !math.IsNaN(v) {
+		return 0, fmt.Errorf("%w %v decoding as %v", def.ErrFloatTruncated, v, k)
+	}
+	if n.IntOverflow == IntOverflowSaturate && !math.IsNaN(v) {
+		switch {
+		case v < -1<<63:
+			return math.MinInt64, nil
+		case v >= 1<<63:
+			return math.MaxInt64, nil
+		}
+	}
+	return decodingutil.Int64FromFloat64(v, k)
+}
+
+func intBounds(k reflect.Kind) (int64, int64) {
+	switch k {
+	case reflect.Int8:
+		return math.MinInt8, math.MaxInt8
+	case reflect.Int16:
+		return math.MinInt16, math.MaxInt16
+	case reflect.Int32:
+		return math.MinInt32, math.MaxInt32
+	case reflect.Int:
+		if def.IsIntSize32 {
+			return math.MinInt32, math.MaxInt32
+		}
+	}
+	return math.MinInt64, math.MaxInt64
+}
+
+func uintBound(k reflect.Kind) uint64 {
+	switch k {
+	case reflect.Uint8:
+		return math.MaxUint8
+	case reflect.Uint16:
+		return math.MaxUint16
+	case reflect.Uint32:
+		return math.MaxUint32
+	case reflect.Uint:
+		if def.IsIntSize32 {
+			return math.MaxUint32
+		}
+	}
+	return math.MaxUint64
+}
@@ -0,0 +1,91 @@
+package common
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/shamaton/msgpack/v3/def"
+)
+
+// TimeType is the type that TimeFormat applies to.
+var TimeType = reflect.TypeOf(time.Time{})
+
+// TimeFormat selects how time.Time values are represented.
+type TimeFormat int
+
+const (
+	// TimeFormatExt is the timestamp extension type.
+	TimeFormatExt TimeFormat = iota
+	// TimeFormatUnix is an integer of seconds since the Unix epoch.
+	TimeFormatUnix
+	// TimeFormatUnixMilli is an integer of milliseconds since the Unix epoch.
+	TimeFormatUnixMilli
+	// TimeFormatUnixFloat is a float of seconds since the Unix epoch.
+	TimeFormatUnixFloat
+	// TimeFormatRFC3339 is a string formatted by time.RFC3339Nano.
+	TimeFormatRFC3339
+)
+
+var timeFormatNames = map[string]TimeFormat{
+	"ext":       TimeFormatExt,
+	"unix":      TimeFormatUnix,
+	"unixms":    TimeFormatUnixMilli,
+	"unixfloat": TimeFormatUnixFloat,
+	"rfc3339":   TimeFormatRFC3339,
+}
+
+// timeFormatOf returns the format of a "time=" tag option, such as
+// "time=unixms". Unknown names are ignored.
+func timeFormatOf(option string) (TimeFormat, bool) {
+	name, ok := strings.CutPrefix(option, "time=")
+	if !ok {
+		return 0, false
+	}
+	f, ok := timeFormatNames[name]
+	return f, ok
+}
+
+// TimeValue returns t in the format f, or t itself for TimeFormatExt.
+func TimeValue(t time.Time, f TimeFormat) reflect.Value {
+	switch f {
+	case TimeFormatUnix:
+		return reflect.ValueOf(t.Unix())
+	case TimeFormatUnixMilli:
+		return reflect.ValueOf(t.UnixMilli())
+	case TimeFormatUnixFloat:
+		return reflect.ValueOf(float64(t.Unix()) + float64(t.Nanosecond())/1e9)
+	case TimeFormatRFC3339:
+		return reflect.ValueOf(t.Format(time.RFC3339Nano))
+	}
+	return reflect.ValueOf(t)
+}
+
+// TimeFromInt returns the time of an integer in the format f, which is
+// milliseconds for TimeFormatUnixMilli and seconds otherwise.
+func TimeFromInt(v int64, f TimeFormat) time.Time {
+	if f == TimeFormatUnixMilli {
+		return time.UnixMilli(v)
+	}
+	return time.Unix(v, 0)
+}
+
+// TimeFromFloat returns the time of a float of seconds.
+func TimeFromFloat(v float64) (time.Time, error) {
+	if math.IsNaN(v) || math.IsInf(v, 0) || v < -1<<63 || v >= 1<<63 {
+		return time.Time{}, fmt.Errorf("%w %v decoding as %v", def.ErrValueOutOfRange, v, TimeType)
+	}
+	sec, frac := math.Modf(v)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9))), nil
+}
+
+// TimeFromString parses an RFC 3339 string.
+func TimeFromString(v string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %v", def.ErrCanNotDecode, err)
+	}
+	return t, nil
+}
@@ -21,43 +21,36 @@ func (d *decoder) asBin(offset int, k reflect.Kind) ([]byte, int, error) {
 		return emptyBytes, 0, err
 	}
 
+	var l int
 	switch code {
 	case def.Bin8:
-		l, offset, err := d.readSize1(offset)
+		b, o, err := d.readSize1(offset)
 		if err != nil {
 			return emptyBytes, 0, err
 		}
-		v, offset, err := d.readSizeN(offset, int(uint8(l)))
-		if err != nil {
-			return emptyBytes, 0, err
-		}
-		return v, offset, nil
+		l, offset = int(b), o
 	case def.Bin16:
-		bs, offset, err := d.readSize2(offset)
-		if err != nil {
-			return emptyBytes, 0, err
-		}
-		v, offset, err := d.readSizeN(offset, int(binary.BigEndian.Uint16(bs)))
+		bs, o, err := d.readSize2(offset)
 		if err != nil {
 			return emptyBytes, 0, err
 		}
-		return v, offset, nil
+		l, offset = int(binary.BigEndian.Uint16(bs)), o
 	case def.Bin32:
-		bs, offset, err := d.readSize4(offset)
+		bs, o, err := d.readSize4(offset)
 		if err != nil {
 			return emptyBytes, 0, err
 		}
-		v, offset, err := d.readSizeN(offset, int(binary.BigEndian.Uint32(bs)))
-		if err != nil {
-			return emptyBytes, 0, err
-		}
-		return v, offset, nil
+		l, offset = int(binary.BigEndian.Uint32(bs)), o
+	default:
+		return emptyBytes, 0, d.errorTemplate(code, k)
 	}
-
-	return emptyBytes, 0, d.errorTemplate(code, k)
+	if err = d.limits.CheckBinLen(l); err != nil {
+		return emptyBytes, 0, err
+	}
+	return d.readSizeN(offset, l)
 }
 
 func (d *decoder) asBinString(offset int, k reflect.Kind) (string, int, error) {
 	bs, offset, err := d.asBin(offset, k)
-	return string(bs), offset, err
+	return d.string(bs), offset, err
 }
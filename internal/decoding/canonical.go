@@ -0,0 +1,278 @@
+package decoding
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common"
+	"github.com/shamaton/msgpack/v3/internal/common/encodingutil"
+)
+
+// IsCanonical checks that data holds exactly one value in canonical form.
+// The returned error reports the offset of the first non-canonical value.
+func IsCanonical(data []byte) error {
+	d := decoder{data: data, maxDepth: common.DefaultMaxDepth}
+	if len(d.data) < 1 {
+		return def.ErrNoData
+	}
+
+	last, err := d.checkCanonical(0)
+	if err != nil {
+		return err
+	}
+	if len(data) != last {
+		return fmt.Errorf("%w size=%d, last=%d", def.ErrHasLeftOver, len(data), last)
+	}
+	return nil
+}
+
+func (d *decoder) notCanonical(offset int, format string, args ...interface{}) error {
+	return fmt.Errorf("%w at offset %d: %s", def.ErrNotCanonical, offset, fmt.Sprintf(format, args...))
+}
+
+func (d *decoder) checkCanonical(offset int) (int, error) {
+	code, _, err := d.readSize1(offset)
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case d.isPositiveFixNum(code), d.isNegativeFixNum(code):
+		return offset + def.Byte1, nil
+
+	case code == def.Nil, code == def.True, code == def.False:
+		return offset + def.Byte1, nil
+
+	case code == def.Uint8, code == def.Uint16, code == def.Uint32, code == def.Uint64:
+		v, o, err := d.asUint(offset, reflect.Uint64)
+		if err != nil {
+			return 0, err
+		}
+		if canonicalUintCode(v) != code {
+			return 0, d.notCanonical(offset, "uint %d is not in the shortest format", v)
+		}
+		return o, nil
+
+	case code == def.Int8, code == def.Int16, code == def.Int32, code == def.Int64:
+		v, o, err := d.asInt(offset, reflect.Int64)
+		if err != nil {
+			return 0, err
+		}
+		if v >= 0 || canonicalIntCode(v) != code {
+			return 0, d.notCanonical(offset, "int %d is not in the shortest format", v)
+		}
+		return o, nil
+
+	case code == def.Float32:
+		bs, o, err := d.readSize4(offset + def.Byte1)
+		if err != nil {
+			return 0, err
+		}
+		bits := binary.BigEndian.Uint32(bs)
+		if math.IsNaN(float64(math.Float32frombits(bits))) && bits != encodingutil.CanonicalNaN32 {
+			return 0, d.notCanonical(offset, "NaN is not %#x", encodingutil.CanonicalNaN32)
+		}
+		return o, nil
+
+	case code == def.Float64:
+		bs, o, err := d.readSize8(offset + def.Byte1)
+		if err != nil {
+			return 0, err
+		}
+		v := math.Float64frombits(binary.BigEndian.Uint64(bs))
+		if math.IsNaN(v) || encodingutil.IsFloat32Exact(v) {
+			return 0, d.notCanonical(offset, "float64 %v fits in float32", v)
+		}
+		return o, nil
+
+	case d.isCodeString(code):
+		l, o, err := d.stringByteLength(offset, reflect.String)
+		if err != nil {
+			return 0, err
+		}
+		if canonicalStringCode(l) != code {
+			return 0, d.notCanonical(offset, "string length %d is not in the shortest format", l)
+		}
+		_, o, err = d.readSizeN(o, l)
+		return o, err
+
+	case d.isCodeBin(code):
+		bs, o, err := d.asBin(offset, reflect.Slice)
+		if err != nil {
+			return 0, err
+		}
+		if canonicalBinCode(len(bs)) != code {
+			return 0, d.notCanonical(offset, "bin length %d is not in the shortest format", len(bs))
+		}
+		return o, nil
+
+	case d.isFixSlice(code), code == def.Array16, code == def.Array32:
+		l, o, err := d.sliceLength(offset, reflect.Slice)
+		if err != nil {
+			return 0, err
+		}
+		if canonicalArrayCode(l) != code {
+			return 0, d.notCanonical(offset, "array length %d is not in the shortest format", l)
+		}
+		if err = d.enter(); err != nil {
+			return 0, err
+		}
+		defer d.leave()
+		for i := 0; i < l; i++ {
+			o, err = d.checkCanonical(o)
+			if err != nil {
+				return 0, err
+			}
+		}
+		return o, nil
+
+	case d.isFixMap(code), code == def.Map16, code == def.Map32:
+		l, o, err := d.mapLength(offset, reflect.Map)
+		if err != nil {
+			return 0, err
+		}
+		if canonicalMapCode(l) != code {
+			return 0, d.notCanonical(offset, "map length %d is not in the shortest format", l)
+		}
+		if err = d.enter(); err != nil {
+			return 0, err
+		}
+		defer d.leave()
+		var prev []byte
+		for i := 0; i < l; i++ {
+			keyOffset := o
+			o, err = d.checkCanonical(o)
+			if err != nil {
+				return 0, err
+			}
+			key := d.data[keyOffset:o]
+			if i > 0 && bytes.Compare(prev, key) >= 0 {
+				return 0, d.notCanonical(keyOffset, "map key is duplicated or not sorted")
+			}
+			prev = key
+			o, err = d.checkCanonical(o)
+			if err != nil {
+				return 0, err
+			}
+		}
+		return o, nil
+	}
+
+	isExt, o, err := d.extEndOffset(offset)
+	if err != nil {
+		return 0, err
+	}
+	if !isExt {
+		return 0, d.errorTemplate(code, reflect.Invalid)
+	}
+	// payload size without the type byte
+	size := o - offset - def.Byte1 - def.Byte1
+	switch code {
+	case def.Ext8:
+		size -= def.Byte1
+	case def.Ext16:
+		size -= def.Byte2
+	case def.Ext32:
+		size -= def.Byte4
+	}
+	if canonicalExtCode(size) != code {
+		return 0, d.notCanonical(offset, "ext length %d is not in the shortest format", size)
+	}
+	return o, nil
+}
+
+func canonicalUintCode(v uint64) byte {
+	switch {
+	case v <= math.MaxInt8:
+		return byte(v) // #nosec G115 -- v is checked to fit positive fixint.
+	case v <= math.MaxUint8:
+		return def.Uint8
+	case v <= math.MaxUint16:
+		return def.Uint16
+	case v <= math.MaxUint32:
+		return def.Uint32
+	}
+	return def.Uint64
+}
+
+func canonicalIntCode(v int64) byte {
+	switch {
+	case v >= def.NegativeFixintMin:
+		return byte(v) // #nosec G115 -- negative fixint is the low-order byte of v.
+	case v >= math.MinInt8:
+		return def.Int8
+	case v >= math.MinInt16:
+		return def.Int16
+	case v >= math.MinInt32:
+		return def.Int32
+	}
+	return def.Int64
+}
+
+func canonicalStringCode(l int) byte {
+	switch {
+	case l < 32:
+		return byte(def.FixStr + l) // #nosec G115 -- l is checked to fit fixstr.
+	case l <= math.MaxUint8:
+		return def.Str8
+	case l <= math.MaxUint16:
+		return def.Str16
+	}
+	return def.Str32
+}
+
+func canonicalBinCode(l int) byte {
+	switch {
+	case l <= math.MaxUint8:
+		return def.Bin8
+	case l <= math.MaxUint16:
+		return def.Bin16
+	}
+	return def.Bin32
+}
+
+func canonicalArrayCode(l int) byte {
+	switch {
+	case l <= 0x0f:
+		return byte(def.FixArray + l) // #nosec G115 -- l is checked to fit fixarray.
+	case l <= math.MaxUint16:
+		return def.Array16
+	}
+	return def.Array32
+}
+
+func canonicalMapCode(l int) byte {
+	switch {
+	case l <= 0x0f:
+		return byte(def.FixMap + l) // #nosec G115 -- l is checked to fit fixmap.
+	case l <= math.MaxUint16:
+		return def.Map16
+	}
+	return def.Map32
+}
+
+func canonicalExtCode(size int) byte {
+	switch size {
+	case def.Byte1:
+		return def.Fixext1
+	case def.Byte2:
+		return def.Fixext2
+	case def.Byte4:
+		return def.Fixext4
+	case def.Byte8:
+		return def.Fixext8
+	case def.Byte16:
+		return def.Fixext16
+	}
+	switch {
+	case size <= math.MaxUint8:
+		return def.Ext8
+	case size <= math.MaxUint16:
+		return def.Ext16
+	}
+	return def.Ext32
+}
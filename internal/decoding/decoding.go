@@ -6,23 +6,57 @@ import (
 
 	"github.com/shamaton/msgpack/v3/def"
 	"github.com/shamaton/msgpack/v3/internal/common"
-	"github.com/shamaton/msgpack/v3/internal/common/decodingutil"
 )
 
 type decoder struct {
-	data    []byte
-	asArray bool
+	data     []byte
+	asArray  bool
+	zeroCopy bool
+	interner *common.Interner
+	depth    int
+	maxDepth int
+	limits   common.Limits
+	// continueOnError skips failed values and records them in errs
+	continueOnError bool
+	errs            def.DecodeErrors
+	weakTypes       bool
+	narrowing       common.Narrowing
+	formats         common.Formats
+	// fixedTypes allows slices and maps of basic types to be decoded in bulk
+	fixedTypes bool
 	common.Common
 }
 
 // Decode analyzes the MessagePack-encoded data and stores
 // the result into the pointer of v.
 func Decode(data []byte, v interface{}, asArray bool) error {
-	d := decoder{data: data, asArray: asArray}
+	return DecodeWithOption(data, v, common.DecodeOption{AsArray: asArray})
+}
+
+// DecodeWithOption analyzes the MessagePack-encoded data and stores
+// the result into the pointer of v according to opt.
+func DecodeWithOption(data []byte, v interface{}, opt common.DecodeOption) error {
+	d := decoder{
+		data:     data,
+		asArray:  opt.AsArray,
+		zeroCopy: opt.ZeroCopy,
+		interner: opt.Interner,
+		maxDepth: opt.Depth(),
+		limits:   opt.Limits,
+
+		continueOnError: opt.ContinueOnError,
+		weakTypes:       opt.WeakTypes,
+		narrowing:       opt.Narrowing,
+		formats:         common.Formats{Time: opt.TimeFormat, Duration: opt.DurationFormat},
+		fixedTypes:      opt.FixedTypes(),
+	}
 
 	if len(d.data) < 1 {
 		return def.ErrNoData
 	}
+	if err := d.limits.CheckTotalBytes(len(d.data)); err != nil {
+		return err
+	}
 	rv := reflect.ValueOf(v)
 	if rv.Kind() != reflect.Ptr {
 		return fmt.Errorf("%w. v.(type): %T", def.ErrReceiverNotPointer, v)
@@ -31,16 +65,56 @@ func Decode(data []byte, v interface{}, asArray bool) error {
 	rv = rv.Elem()
 
 	last, err := d.decode(rv, 0)
+	if err == nil && len(data) != last {
+		return fmt.Errorf("%w size=%d, last=%d", def.ErrHasLeftOver, len(data), last)
+	}
 	if err != nil {
-		return err
+		de, ok := err.(*def.DecodeError)
+		if !d.continueOnError || !ok {
+			return common.RootPath(err, rv.Type())
+		}
+		d.errs = append(d.errs, de)
 	}
-	if len(data) != last {
-		return fmt.Errorf("%w size=%d, last=%d", def.ErrHasLeftOver, len(data), last)
+	if len(d.errs) > 0 {
+		for _, e := range d.errs {
+			common.RootPath(e, rv.Type())
+		}
+		return d.errs
 	}
-	return err
+	return nil
 }
 
 func (d *decoder) decode(rv reflect.Value, offset int) (int, error) {
+	o, err := d.decodeValue(rv, offset)
+	if err != nil {
+		return 0, d.decodeError(err, offset, rv.Type())
+	}
+	return o, nil
+}
+
+func (d *decoder) decodeValue(rv reflect.Value, offset int) (int, error) {
+	// every value has at least its code byte, which some cases read directly
+	if offset >= len(d.data) {
+		return 0, def.ErrTooShortBytes
+	}
+	if extTypeCoders != nil {
+		if c, ok := extTypeCoders[rv.Type()]; ok {
+			if o, found, err := d.asExtType(c, rv, offset); found {
+				return o, err
+			}
+		}
+	}
+	if d.formats.Duration != common.DurationFormatNanos && rv.Type() == common.DurationType {
+		if o, found, err := d.asDuration(rv, offset); found {
+			return o, err
+		}
+	}
+	if d.weakTypes {
+		if o, found, err := d.asWeak(rv, offset); found {
+			return o, err
+		}
+	}
+
 	k := rv.Kind()
 	switch k {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -48,7 +122,7 @@ func (d *decoder) decode(rv reflect.Value, offset int) (int, error) {
 		if err != nil {
 			return 0, err
 		}
-		v, err = decodingutil.IntValueForKind(v, k)
+		v, err = d.narrowing.Int(v, k)
 		if err != nil {
 			return 0, err
 		}
@@ -60,7 +134,7 @@ func (d *decoder) decode(rv reflect.Value, offset int) (int, error) {
 		if err != nil {
 			return 0, err
 		}
-		v, err = decodingutil.UintValueForKind(v, k)
+		v, err = d.narrowing.Uint(v, k)
 		if err != nil {
 			return 0, err
 		}
@@ -148,26 +222,35 @@ func (d *decoder) decode(rv reflect.Value, offset int) (int, error) {
 		}
 
 		// check fixed type
-		fixedOffset, found, err := d.asFixedSlice(rv, o, l)
-		if err != nil {
-			return 0, err
+		if d.fixedTypes {
+			fixedOffset, found, err := d.asFixedSlice(rv, o, l)
+			if err != nil {
+				return 0, err
+			}
+			if found {
+				return fixedOffset, nil
+			}
 		}
-		if found {
-			return fixedOffset, nil
+
+		if err = d.enter(); err != nil {
+			return 0, err
 		}
+		defer d.leave()
 
 		// create slice dynamically
 		tmpSlice := reflect.MakeSlice(rv.Type(), l, l)
 		for i := 0; i < l; i++ {
-			v := tmpSlice.Index(i)
-			if v.Kind() == reflect.Struct {
-				o, err = d.setStruct(v, o, k)
-			} else {
-				o, err = d.decode(v, o)
-			}
+			n := len(d.errs)
+			o2, err := d.decode(tmpSlice.Index(i), o)
 			if err != nil {
-				return 0, err
+				o2, err = d.skipValue(err, o)
 			}
+			if err != nil || len(d.errs) > n {
+				if err = d.elementPath(err, n, common.IndexPath(i)); err != nil {
+					return 0, err
+				}
+			}
+			o = o2
 		}
 		rv.Set(tmpSlice)
 		offset = o
@@ -241,12 +324,24 @@ func (d *decoder) decode(rv reflect.Value, offset int) (int, error) {
 			return 0, err
 		}
 
+		if err = d.enter(); err != nil {
+			return 0, err
+		}
+		defer d.leave()
+
 		// create array dynamically
 		for i := 0; i < l; i++ {
-			o, err = d.decode(rv.Index(i), o)
+			n := len(d.errs)
+			o2, err := d.decode(rv.Index(i), o)
 			if err != nil {
-				return 0, err
+				o2, err = d.skipValue(err, o)
+			}
+			if err != nil || len(d.errs) > n {
+				if err = d.elementPath(err, n, common.IndexPath(i)); err != nil {
+					return 0, err
+				}
 			}
+			o = o2
 		}
 		offset = o
 
@@ -268,13 +363,20 @@ func (d *decoder) decode(rv reflect.Value, offset int) (int, error) {
 		}
 
 		// check fixed type
-		fixedOffset, found, err := d.asFixedMap(rv, o, l)
-		if err != nil {
-			return 0, err
+		if d.fixedTypes {
+			fixedOffset, found, err := d.asFixedMap(rv, o, l)
+			if err != nil {
+				return 0, err
+			}
+			if found {
+				return fixedOffset, nil
+			}
 		}
-		if found {
-			return fixedOffset, nil
+
+		if err = d.enter(); err != nil {
+			return 0, err
 		}
+		defer d.leave()
 
 		// create dynamically
 		key := rv.Type().Key()
@@ -289,16 +391,42 @@ func (d *decoder) decode(rv reflect.Value, offset int) (int, error) {
 			if err != nil {
 				return 0, err
 			}
-			o, err = d.decode(v, o)
+			n := len(d.errs)
+			o2, err := d.decode(v, o)
+			decoded := err == nil
 			if err != nil {
-				return 0, err
+				o2, err = d.skipValue(err, o)
+			}
+			if err != nil || len(d.errs) > n {
+				if err = d.elementPath(err, n, common.KeyPath(k)); err != nil {
+					return 0, err
+				}
 			}
+			o = o2
 
-			rv.SetMapIndex(k, v)
+			// skipped values are left out of the map
+			if decoded {
+				rv.SetMapIndex(k, v)
+			}
 		}
 		offset = o
 
 	case reflect.Struct:
+		if rv.Type() == common.ExtType {
+			v, o, found, err := d.asRawExt(offset)
+			if err != nil {
+				return 0, err
+			}
+			if found {
+				rv.Set(reflect.ValueOf(v))
+				return o, nil
+			}
+		}
+		if rv.Type() == common.TimeType && d.formats.Time != common.TimeFormatExt {
+			if o, found, err := d.asTime(rv, offset); found {
+				return o, err
+			}
+		}
 		o, err := d.setStruct(rv, offset, k)
 		if err != nil {
 			return 0, err
@@ -347,6 +475,61 @@ func (d *decoder) decode(rv reflect.Value, offset int) (int, error) {
 	return offset, nil
 }
 
+// enter increases the nesting depth before the elements of a container
+// are decoded and fails when it would exceed the limit, leaving the depth
+// as it is. Zero means no limit.
+func (d *decoder) enter() error {
+	if d.depth >= d.maxDepth && d.maxDepth > 0 {
+		return fmt.Errorf("%w: %d", def.ErrMaxDepthExceeded, d.maxDepth)
+	}
+	d.depth++
+	return nil
+}
+
+// leave decreases the nesting depth after a successful enter, which
+// callers defer so that errors do not leave the depth raised.
+func (d *decoder) leave() {
+	d.depth--
+}
+
+// skipValue records err of the value at offset and skips the value when
+// continueOnError is set. Otherwise, or when the value cannot be skipped,
+// it returns err.
+func (d *decoder) skipValue(err error, offset int) (int, error) {
+	de, ok := err.(*def.DecodeError)
+	if !d.continueOnError || !ok {
+		return 0, err
+	}
+	o, jumpErr := d.jumpOffset(offset)
+	if jumpErr != nil {
+		return 0, err
+	}
+	d.errs = append(d.errs, de)
+	return o, nil
+}
+
+// elementPath prepends elem to the path of err, or to the paths of the
+// errors recorded since the n-th when err is nil.
+func (d *decoder) elementPath(err error, n int, elem string) error {
+	if err != nil {
+		return common.ErrorPath(err, elem)
+	}
+	for _, e := range d.errs[n:] {
+		e.PrependPath(elem)
+	}
+	return nil
+}
+
+// decodeError records the offset, code and type of the value at offset
+// in err.
+func (d *decoder) decodeError(err error, offset int, t reflect.Type) error {
+	var code byte
+	if offset < len(d.data) {
+		code = d.data[offset]
+	}
+	return common.DecodeError(err, offset, code, t)
+}
+
 func (d *decoder) errorTemplate(code byte, k reflect.Kind) error {
 	return fmt.Errorf("%w %x decoding as %v", def.ErrCanNotDecode, code, k)
 }
@@ -0,0 +1,61 @@
+package decoding
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common"
+)
+
+// asDuration decodes the value at offset into rv of time.Duration when it
+// is an integer, a float or a string. found is false for other formats,
+// which are decoded as usual.
+func (d *decoder) asDuration(rv reflect.Value, offset int) (int, bool, error) {
+	code, _, err := d.readSize1(offset)
+	if err != nil {
+		return 0, false, nil
+	}
+
+	var v time.Duration
+	switch {
+	case d.isCodeInt(code), d.isCodeUint(code):
+		i, o, err := d.asInt(offset, reflect.Int64)
+		if err != nil {
+			return 0, true, err
+		}
+		v, err = common.DurationFromInt(i, d.formats.Duration)
+		if err != nil {
+			return 0, true, err
+		}
+		offset = o
+
+	case code == def.Float32, code == def.Float64:
+		f, o, err := d.asFloat64(offset, reflect.Float64)
+		if err != nil {
+			return 0, true, err
+		}
+		v, err = common.DurationFromFloat(f)
+		if err != nil {
+			return 0, true, err
+		}
+		offset = o
+
+	case d.isCodeString(code):
+		s, o, err := d.asString(offset, reflect.String)
+		if err != nil {
+			return 0, true, err
+		}
+		v, err = common.DurationFromString(s)
+		if err != nil {
+			return 0, true, err
+		}
+		offset = o
+
+	default:
+		return 0, false, nil
+	}
+
+	rv.SetInt(int64(v))
+	return offset, true, nil
+}
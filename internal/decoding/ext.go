@@ -2,21 +2,35 @@ package decoding
 
 import (
 	"encoding/binary"
+	"reflect"
 
 	"github.com/shamaton/msgpack/v3/def"
 	"github.com/shamaton/msgpack/v3/ext"
+	"github.com/shamaton/msgpack/v3/internal/common"
 	"github.com/shamaton/msgpack/v3/time"
 )
 
 var (
-	extCoderMap = map[int8]ext.Decoder{time.Decoder.Code(): time.Decoder}
-	extCoders   = []ext.Decoder{time.Decoder}
+	timeDecoder = ext.AdaptDecoder(time.Decoder)
+	extCoderMap = map[int8]ext.DecoderV2{timeDecoder.Code(): timeDecoder}
+	// extTypeCoders holds the decoders of named types other than structs,
+	// which are looked up for every value
+	extTypeCoders map[reflect.Type]ext.DecoderV2
+	extCoders     = []ext.DecoderV2{timeDecoder}
+	// extIntCoders holds the decoders that accept plain integers
+	extIntCoders []ext.DecoderV2
 )
 
 // AddExtDecoder adds decoders for extension types.
 func AddExtDecoder(f ext.Decoder) {
+	AddExtDecoderV2(ext.AdaptDecoder(f))
+}
+
+// AddExtDecoderV2 adds decoders for extension types that decode into
+// their destination.
+func AddExtDecoderV2(f ext.DecoderV2) {
 	// ignore time
-	if f.Code() == time.Decoder.Code() {
+	if f.Code() == timeDecoder.Code() {
 		return
 	}
 
@@ -29,8 +43,14 @@ func AddExtDecoder(f ext.Decoder) {
 
 // RemoveExtDecoder removes decoders for extension types.
 func RemoveExtDecoder(f ext.Decoder) {
+	RemoveExtDecoderV2(ext.AdaptDecoder(f))
+}
+
+// RemoveExtDecoderV2 removes decoders for extension types that decode
+// into their destination.
+func RemoveExtDecoderV2(f ext.DecoderV2) {
 	// ignore time
-	if f.Code() == time.Decoder.Code() {
+	if f.Code() == timeDecoder.Code() {
 		return
 	}
 
@@ -41,12 +61,78 @@ func RemoveExtDecoder(f ext.Decoder) {
 	}
 }
 
+// AddExtTypeDecoder adds the decoder of t, a named type other than a struct,
+// which is used for every value decoded into t.
+func AddExtTypeDecoder(t reflect.Type, f ext.DecoderV2) {
+	if extTypeCoders == nil {
+		extTypeCoders = map[reflect.Type]ext.DecoderV2{}
+	}
+	extTypeCoders[t] = f
+}
+
+// RemoveExtTypeDecoder removes the decoder of t.
+func RemoveExtTypeDecoder(t reflect.Type) {
+	delete(extTypeCoders, t)
+	if len(extTypeCoders) == 0 {
+		extTypeCoders = nil
+	}
+}
+
+// asExtType decodes the value at offset into rv with c, the decoder of
+// the type of rv. Extensions, and integers when c accepts them, are
+// offered to c, and found is false when it does not accept them, so that
+// they are decoded as usual.
+func (d *decoder) asExtType(c ext.DecoderV2, rv reflect.Value, offset int) (int, bool, error) {
+	isExt, _, err := d.extEndOffset(offset)
+	if err != nil {
+		return 0, true, err
+	}
+	if code := d.data[offset]; !isExt && (!ext.AcceptsInts(c) || !d.isCodeInt(code) && !d.isCodeUint(code)) {
+		return 0, false, nil
+	}
+	if !c.IsType(offset, &d.data) {
+		return 0, false, nil
+	}
+
+	offset, err = c.DecodeValue(offset, rv, &d.data)
+	if err != nil {
+		return 0, true, err
+	}
+	return offset, true, nil
+}
+
+// asRawExt returns the extension at offset as it is encoded. found is
+// false when the value is not an extension.
+func (d *decoder) asRawExt(offset int) (common.Ext, int, bool, error) {
+	code := d.data[offset]
+	isExt, end, err := d.extEndOffsetWithCode(code, offset+1)
+	if !isExt || err != nil {
+		return common.Ext{}, 0, isExt, err
+	}
+	// the type byte follows the code and the length of the data
+	start := offset + def.Byte1
+	switch code {
+	case def.Ext8:
+		start += def.Byte1
+	case def.Ext16:
+		start += def.Byte2
+	case def.Ext32:
+		start += def.Byte4
+	}
+	typ := int8(d.data[start]) // #nosec G115 -- the type byte is a signed extension type.
+	return common.Ext{Type: typ, Data: d.data[start+def.Byte1 : end]}, end, true, nil
+}
+
 func updateExtCoders() {
-	extCoders = make([]ext.Decoder, len(extCoderMap))
+	extCoders = make([]ext.DecoderV2, len(extCoderMap))
+	extIntCoders = nil
 	i := 0
 	for k := range extCoderMap {
 		extCoders[i] = extCoderMap[k]
 		i++
+		if ext.AcceptsInts(extCoderMap[k]) {
+			extIntCoders = append(extIntCoders, extCoderMap[k])
+		}
 	}
 }
 
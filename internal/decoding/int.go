@@ -94,7 +94,7 @@ func (d *decoder) asInt(offset int, k reflect.Kind) (int64, int, error) {
 		if err != nil {
 			return 0, 0, err
 		}
-		v, err := decodingutil.Int64FromUint64(binary.BigEndian.Uint64(bs), k)
+		v, err := d.narrowing.IntFromUint(binary.BigEndian.Uint64(bs), k)
 		if err != nil {
 			return 0, 0, err
 		}
@@ -113,7 +113,7 @@ func (d *decoder) asInt(offset int, k reflect.Kind) (int64, int, error) {
 		if err != nil {
 			return 0, 0, err
 		}
-		vv, err := decodingutil.Int64FromFloat64(float64(v), k)
+		vv, err := d.narrowing.IntFromFloat(float64(v), k)
 		if err != nil {
 			return 0, 0, err
 		}
@@ -124,7 +124,7 @@ func (d *decoder) asInt(offset int, k reflect.Kind) (int64, int, error) {
 		if err != nil {
 			return 0, 0, err
 		}
-		vv, err := decodingutil.Int64FromFloat64(v, k)
+		vv, err := d.narrowing.IntFromFloat(v, k)
 		if err != nil {
 			return 0, 0, err
 		}
@@ -5,9 +5,12 @@ import (
 	"reflect"
 
 	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common"
 	"github.com/shamaton/msgpack/v3/internal/common/decodingutil"
 )
 
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
 func (d *decoder) asInterface(offset int, k reflect.Kind) (interface{}, int, error) {
 	code, _, err := d.readSize1(offset)
 	if err != nil {
@@ -137,11 +140,16 @@ func (d *decoder) asInterface(offset int, k reflect.Kind) (interface{}, int, err
 			return nil, 0, err
 		}
 
+		if err = d.enter(); err != nil {
+			return nil, 0, err
+		}
+		defer d.leave()
 		v := make([]interface{}, l)
 		for i := 0; i < l; i++ {
 			vv, o2, err := d.asInterface(o, k)
 			if err != nil {
-				return nil, 0, err
+				err = d.decodeError(err, o, interfaceType)
+				return nil, 0, common.ErrorPath(err, common.IndexPath(i))
 			}
 			v[i] = vv
 			o = o2
@@ -157,21 +165,26 @@ func (d *decoder) asInterface(offset int, k reflect.Kind) (interface{}, int, err
 		if err = d.hasRequiredLeastMapSize(o, l); err != nil {
 			return nil, 0, err
 		}
+		if err = d.enter(); err != nil {
+			return nil, 0, err
+		}
+		defer d.leave()
 		v := make(map[interface{}]interface{}, l)
 		for i := 0; i < l; i++ {
 			if err := d.canSetAsMapKey(o); err != nil {
-				return nil, 0, err
+				return nil, 0, d.decodeError(err, o, interfaceType)
 			}
 			key, o2, err := d.asInterface(o, k)
 			if err != nil {
-				return nil, 0, err
+				return nil, 0, d.decodeError(err, o, interfaceType)
 			}
-			value, o2, err := d.asInterface(o2, k)
+			value, o3, err := d.asInterface(o2, k)
 			if err != nil {
-				return nil, 0, err
+				err = d.decodeError(err, o2, interfaceType)
+				return nil, 0, common.ErrorPath(err, common.KeyPath(reflect.ValueOf(key)))
 			}
 			v[key] = value
-			o = o2
+			o = o3
 		}
 		offset = o
 		return v, offset, nil
@@ -195,13 +208,20 @@ func (d *decoder) asInterface(offset int, k reflect.Kind) (interface{}, int, err
 	if isExt {
 		for i := range extCoders {
 			if extCoders[i].IsType(offset, &d.data) {
-				v, offset, err := extCoders[i].AsValue(offset, k, &d.data)
+				rv := reflect.New(interfaceType).Elem()
+				offset, err := extCoders[i].DecodeValue(offset, rv, &d.data)
 				if err != nil {
 					return nil, 0, err
 				}
-				return v, offset, nil
+				return rv.Interface(), offset, nil
 			}
 		}
+		// the type has no decoder, so it is passed through as it is
+		v, offset, _, err := d.asRawExt(offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		return v, offset, nil
 	}
 	return nil, 0, d.errorTemplate(code, k)
 }
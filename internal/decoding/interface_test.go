@@ -7,6 +7,7 @@ import (
 
 	"github.com/shamaton/msgpack/v3/def"
 	"github.com/shamaton/msgpack/v3/ext"
+	"github.com/shamaton/msgpack/v3/internal/common"
 )
 
 func Test_asInterfaceWithCode(t *testing.T) {
@@ -162,6 +163,18 @@ func Test_asInterfaceWithCode(t *testing.T) {
 			Error:    ErrTestExtDecoder,
 			MethodAs: method,
 		},
+		{
+			Name:     "Ext.raw",
+			Data:     []byte{def.Fixext1, 4, 0},
+			Expected: common.Ext{Type: 4, Data: []byte{0}},
+			MethodAs: method,
+		},
+		{
+			Name:     "Ext.raw.empty",
+			Data:     []byte{def.Ext8, 0, 0xfc},
+			Expected: common.Ext{Type: -4, Data: []byte{}},
+			MethodAs: method,
+		},
 	}
 
 	for _, tc := range testcases {
@@ -39,23 +39,29 @@ func (d *decoder) sliceLength(offset int, k reflect.Kind) (int, int, error) {
 		return 0, 0, err
 	}
 
+	var l int
 	switch {
 	case d.isFixSlice(code):
-		return int(code - def.FixArray), offset, nil
+		l = int(code - def.FixArray)
 	case code == def.Array16:
-		bs, offset, err := d.readSize2(offset)
+		bs, o, err := d.readSize2(offset)
 		if err != nil {
 			return 0, 0, err
 		}
-		return int(binary.BigEndian.Uint16(bs)), offset, nil
+		l, offset = int(binary.BigEndian.Uint16(bs)), o
 	case code == def.Array32:
-		bs, offset, err := d.readSize4(offset)
+		bs, o, err := d.readSize4(offset)
 		if err != nil {
 			return 0, 0, err
 		}
-		return int(binary.BigEndian.Uint32(bs)), offset, nil
+		l, offset = int(binary.BigEndian.Uint32(bs)), o
+	default:
+		return 0, 0, d.errorTemplate(code, k)
 	}
-	return 0, 0, d.errorTemplate(code, k)
+	if err = d.limits.CheckArrayLen(l); err != nil {
+		return 0, 0, err
+	}
+	return l, offset, nil
 }
 
 func (d *decoder) hasRequiredLeastSliceSize(offset, length int) error {
@@ -3,8 +3,10 @@ package decoding
 import (
 	"encoding/binary"
 	"reflect"
+	"unsafe"
 
 	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common"
 )
 
 var (
@@ -26,31 +28,36 @@ func (d *decoder) stringByteLength(offset int, k reflect.Kind) (int, int, error)
 		return 0, 0, err
 	}
 
+	var l int
 	if def.FixStr <= code && code <= def.FixStr+0x1f {
-		l := int(code - def.FixStr)
-		return l, offset, nil
+		l = int(code - def.FixStr)
 	} else if code == def.Str8 {
-		b, offset, err := d.readSize1(offset)
+		b, o, err := d.readSize1(offset)
 		if err != nil {
 			return 0, 0, err
 		}
-		return int(b), offset, nil
+		l, offset = int(b), o
 	} else if code == def.Str16 {
-		b, offset, err := d.readSize2(offset)
+		b, o, err := d.readSize2(offset)
 		if err != nil {
 			return 0, 0, err
 		}
-		return int(binary.BigEndian.Uint16(b)), offset, nil
+		l, offset = int(binary.BigEndian.Uint16(b)), o
 	} else if code == def.Str32 {
-		b, offset, err := d.readSize4(offset)
+		b, o, err := d.readSize4(offset)
 		if err != nil {
 			return 0, 0, err
 		}
-		return int(binary.BigEndian.Uint32(b)), offset, nil
+		l, offset = int(binary.BigEndian.Uint32(b)), o
 	} else if code == def.Nil {
 		return 0, offset, nil
+	} else {
+		return 0, 0, d.errorTemplate(code, k)
 	}
-	return 0, 0, d.errorTemplate(code, k)
+	if err = d.limits.CheckStringLen(l); err != nil {
+		return 0, 0, err
+	}
+	return l, offset, nil
 }
 
 func (d *decoder) asString(offset int, k reflect.Kind) (string, int, error) {
@@ -58,7 +65,7 @@ func (d *decoder) asString(offset int, k reflect.Kind) (string, int, error) {
 	if err != nil {
 		return emptyString, 0, err
 	}
-	return string(bs), offset, nil
+	return d.string(bs), offset, nil
 }
 
 func (d *decoder) asStringByte(offset int, k reflect.Kind) ([]byte, int, error) {
@@ -77,3 +84,18 @@ func (d *decoder) asStringByteByLength(offset int, l int, k reflect.Kind) ([]byt
 
 	return d.readSizeN(offset, l)
 }
+
+// string returns bs as a string. Strings held by the interner are shared,
+// and in zero copy mode the others share memory with the input data.
+func (d *decoder) string(bs []byte) string {
+	if d.interner != nil && len(bs) <= common.InternMaxLen {
+		return d.interner.String(bs)
+	}
+	if d.zeroCopy {
+		if len(bs) < 1 {
+			return emptyString
+		}
+		return unsafe.String(&bs[0], len(bs))
+	}
+	return string(bs)
+}
@@ -2,10 +2,12 @@ package decoding
 
 import (
 	"encoding/binary"
+	"errors"
 	"reflect"
 	"sync"
 
 	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common"
 )
 
 type structCacheTypeMap struct {
@@ -19,6 +21,9 @@ type structCacheTypeMap struct {
 
 	// embedded path (hasEmbedded == true): path-based access
 	indexes [][]int // field path (support for embedded structs)
+
+	// formats holds the formats set by field tags
+	formats map[int]common.FormatTags
 }
 
 type structCacheTypeArray struct {
@@ -30,6 +35,9 @@ type structCacheTypeArray struct {
 
 	// embedded path (hasEmbedded == true): path-based access
 	indexes [][]int // field path (support for embedded structs)
+
+	// formats holds the formats set by field tags
+	formats map[int]common.FormatTags
 }
 
 // struct cache map
@@ -74,27 +82,39 @@ func (d *decoder) setStruct(rv reflect.Value, offset int, k reflect.Kind) (int,
 	if err != nil {
 		return 0, err
 	}
-	if isExt {
-		for i := range extCoders {
-			if extCoders[i].IsType(offset, &d.data) {
-				v, offset, err := extCoders[i].AsValue(offset, k, &d.data)
-				if err != nil {
-					return 0, err
-				}
-
-				// Validate that the receptacle is of the right value type.
-				if rv.Type() == reflect.TypeOf(v) {
-					rv.Set(reflect.ValueOf(v))
-					return offset, nil
-				}
+	coders := extCoders
+	if !isExt {
+		// plain integers are offered to the decoders that accept them,
+		// for types such as big.Int
+		coders = nil
+		if d.isCodeInt(d.data[offset]) || d.isCodeUint(d.data[offset]) {
+			coders = extIntCoders
+		}
+	}
+	for i := range coders {
+		if coders[i].IsType(offset, &d.data) {
+			o, err := coders[i].DecodeValue(offset, rv, &d.data)
+			// another decoder may decode into the type of rv
+			if errors.Is(err, def.ErrExtTypeMismatch) {
+				continue
 			}
+			if err != nil {
+				return 0, err
+			}
+			return o, nil
 		}
 	}
 
+	if err = d.enter(); err != nil {
+		return 0, err
+	}
+	defer d.leave()
 	if d.asArray {
-		return d.setStructFromArray(rv, offset, k)
+		offset, err = d.setStructFromArray(rv, offset, k)
+	} else {
+		offset, err = d.setStructFromMap(rv, offset, k)
 	}
-	return d.setStructFromMap(rv, offset, k)
+	return offset, err
 }
 
 func (d *decoder) setStructFromArray(rv reflect.Value, offset int, k reflect.Kind) (int, error) {
@@ -132,6 +152,7 @@ func (d *decoder) setStructFromArray(rv reflect.Value, offset int, k reflect.Kin
 				scta.simpleIndexes = append(scta.simpleIndexes, field.Path[0])
 			}
 		}
+		scta.formats = common.FieldFormats(fields)
 		mapSCTA.Store(rv.Type(), scta)
 	} else {
 		scta = cache.(*structCacheTypeArray)
@@ -144,10 +165,19 @@ func (d *decoder) setStructFromArray(rv reflect.Value, offset int, k reflect.Kin
 				allowAlloc := !d.isCodeNil(d.data[o])
 				fieldValue, ok := getFieldByPath(rv, scta.indexes[i], allowAlloc)
 				if ok {
-					o, err = d.decode(fieldValue, o)
+					n := len(d.errs)
+					f := d.useFormats(scta.formats, i)
+					o2, err := d.decode(fieldValue, o)
+					d.formats = f
 					if err != nil {
-						return 0, err
+						o2, err = d.skipValue(err, o)
 					}
+					if err != nil || len(d.errs) > n {
+						if err = d.elementPath(err, n, common.FieldPath(rv.Type(), scta.indexes[i])); err != nil {
+							return 0, err
+						}
+					}
+					o = o2
 				} else {
 					o, err = d.jumpOffset(o)
 					if err != nil {
@@ -164,10 +194,19 @@ func (d *decoder) setStructFromArray(rv reflect.Value, offset int, k reflect.Kin
 	} else {
 		for i := 0; i < l; i++ {
 			if i < len(scta.simpleIndexes) {
-				o, err = d.decode(rv.Field(scta.simpleIndexes[i]), o)
+				n := len(d.errs)
+				f := d.useFormats(scta.formats, i)
+				o2, err := d.decode(rv.Field(scta.simpleIndexes[i]), o)
+				d.formats = f
 				if err != nil {
-					return 0, err
+					o2, err = d.skipValue(err, o)
 				}
+				if err != nil || len(d.errs) > n {
+					if err = d.elementPath(err, n, common.FieldPath(rv.Type(), scta.simpleIndexes[i:i+1])); err != nil {
+						return 0, err
+					}
+				}
+				o = o2
 			} else {
 				o, err = d.jumpOffset(o)
 				if err != nil {
@@ -214,6 +253,7 @@ func (d *decoder) setStructFromMap(rv reflect.Value, offset int, k reflect.Kind)
 				sctm.simpleIndexes = append(sctm.simpleIndexes, field.Path[0])
 			}
 		}
+		sctm.formats = common.FieldFormats(fields)
 		mapSCTM.Store(rv.Type(), sctm)
 	} else {
 		sctm = cache.(*structCacheTypeMap)
@@ -226,7 +266,7 @@ func (d *decoder) setStructFromMap(rv reflect.Value, offset int, k reflect.Kind)
 				return 0, err
 			}
 
-			fieldPath := []int(nil)
+			fieldPath, fieldKey := []int(nil), -1
 			for keyIndex, keyBytes := range sctm.keys {
 				if len(keyBytes) != len(dataKey) {
 					continue
@@ -240,7 +280,7 @@ func (d *decoder) setStructFromMap(rv reflect.Value, offset int, k reflect.Kind)
 					}
 				}
 				if found {
-					fieldPath = sctm.indexes[keyIndex]
+					fieldPath, fieldKey = sctm.indexes[keyIndex], keyIndex
 					break
 				}
 			}
@@ -249,10 +289,19 @@ func (d *decoder) setStructFromMap(rv reflect.Value, offset int, k reflect.Kind)
 				allowAlloc := !d.isCodeNil(d.data[o2])
 				fieldValue, ok := getFieldByPath(rv, fieldPath, allowAlloc)
 				if ok {
-					o2, err = d.decode(fieldValue, o2)
+					n := len(d.errs)
+					f := d.useFormats(sctm.formats, fieldKey)
+					o3, err := d.decode(fieldValue, o2)
+					d.formats = f
 					if err != nil {
-						return 0, err
+						o3, err = d.skipValue(err, o2)
+					}
+					if err != nil || len(d.errs) > n {
+						if err = d.elementPath(err, n, common.FieldPath(rv.Type(), fieldPath)); err != nil {
+							return 0, err
+						}
 					}
+					o2 = o3
 				} else {
 					o2, err = d.jumpOffset(o2)
 					if err != nil {
@@ -274,7 +323,7 @@ func (d *decoder) setStructFromMap(rv reflect.Value, offset int, k reflect.Kind)
 				return 0, err
 			}
 
-			fieldIndex := -1
+			fieldIndex, fieldKey := -1, -1
 			for keyIndex, keyBytes := range sctm.keys {
 				if len(keyBytes) != len(dataKey) {
 					continue
@@ -288,16 +337,25 @@ func (d *decoder) setStructFromMap(rv reflect.Value, offset int, k reflect.Kind)
 					}
 				}
 				if found {
-					fieldIndex = sctm.simpleIndexes[keyIndex]
+					fieldIndex, fieldKey = sctm.simpleIndexes[keyIndex], keyIndex
 					break
 				}
 			}
 
 			if fieldIndex >= 0 {
-				o2, err = d.decode(rv.Field(fieldIndex), o2)
+				n := len(d.errs)
+				f := d.useFormats(sctm.formats, fieldKey)
+				o3, err := d.decode(rv.Field(fieldIndex), o2)
+				d.formats = f
 				if err != nil {
-					return 0, err
+					o3, err = d.skipValue(err, o2)
+				}
+				if err != nil || len(d.errs) > n {
+					if err = d.elementPath(err, n, common.FieldPath(rv.Type(), []int{fieldIndex})); err != nil {
+						return 0, err
+					}
 				}
+				o2 = o3
 			} else {
 				o2, err = d.jumpOffset(o2)
 				if err != nil {
@@ -310,6 +368,16 @@ func (d *decoder) setStructFromMap(rv reflect.Value, offset int, k reflect.Kind)
 	return o, nil
 }
 
+// useFormats applies the format tags of the i-th field in formats and
+// returns the formats to restore afterwards.
+func (d *decoder) useFormats(formats map[int]common.FormatTags, i int) common.Formats {
+	f := d.formats
+	if t, ok := formats[i]; ok {
+		d.formats = t.Apply(f)
+	}
+	return f
+}
+
 func (d *decoder) jumpOffset(offset int) (int, error) {
 	code, offset, err := d.readSize1(offset)
 	if err != nil {
@@ -357,11 +425,9 @@ func (d *decoder) jumpOffset(offset int) (int, error) {
 
 	case d.isFixSlice(code):
 		l := int(code - def.FixArray)
-		for i := 0; i < l; i++ {
-			offset, err = d.jumpOffset(offset)
-			if err != nil {
-				return 0, err
-			}
+		offset, err = d.jumpElements(offset, l)
+		if err != nil {
+			return 0, err
 		}
 	case code == def.Array16:
 		bs, o, err := d.readSize2(offset)
@@ -369,11 +435,9 @@ func (d *decoder) jumpOffset(offset int) (int, error) {
 			return 0, err
 		}
 		l := int(binary.BigEndian.Uint16(bs))
-		for i := 0; i < l; i++ {
-			o, err = d.jumpOffset(o)
-			if err != nil {
-				return 0, err
-			}
+		o, err = d.jumpElements(o, l)
+		if err != nil {
+			return 0, err
 		}
 		offset = o
 	case code == def.Array32:
@@ -382,21 +446,17 @@ func (d *decoder) jumpOffset(offset int) (int, error) {
 			return 0, err
 		}
 		l := int(binary.BigEndian.Uint32(bs))
-		for i := 0; i < l; i++ {
-			o, err = d.jumpOffset(o)
-			if err != nil {
-				return 0, err
-			}
+		o, err = d.jumpElements(o, l)
+		if err != nil {
+			return 0, err
 		}
 		offset = o
 
 	case d.isFixMap(code):
 		l := int(code - def.FixMap)
-		for i := 0; i < l*2; i++ {
-			offset, err = d.jumpOffset(offset)
-			if err != nil {
-				return 0, err
-			}
+		offset, err = d.jumpElements(offset, l*2)
+		if err != nil {
+			return 0, err
 		}
 	case code == def.Map16:
 		bs, o, err := d.readSize2(offset)
@@ -404,11 +464,9 @@ func (d *decoder) jumpOffset(offset int) (int, error) {
 			return 0, err
 		}
 		l := int(binary.BigEndian.Uint16(bs))
-		for i := 0; i < l*2; i++ {
-			o, err = d.jumpOffset(o)
-			if err != nil {
-				return 0, err
-			}
+		o, err = d.jumpElements(o, l*2)
+		if err != nil {
+			return 0, err
 		}
 		offset = o
 	case code == def.Map32:
@@ -417,11 +475,9 @@ func (d *decoder) jumpOffset(offset int) (int, error) {
 			return 0, err
 		}
 		l := int(binary.BigEndian.Uint32(bs))
-		for i := 0; i < l*2; i++ {
-			o, err = d.jumpOffset(o)
-			if err != nil {
-				return 0, err
-			}
+		o, err = d.jumpElements(o, l*2)
+		if err != nil {
+			return 0, err
 		}
 		offset = o
 
@@ -437,3 +493,19 @@ func (d *decoder) jumpOffset(offset int) (int, error) {
 	}
 	return offset, nil
 }
+
+// jumpElements skips n consecutive values inside a container.
+func (d *decoder) jumpElements(offset, n int) (int, error) {
+	if err := d.enter(); err != nil {
+		return 0, err
+	}
+	defer d.leave()
+	var err error
+	for i := 0; i < n; i++ {
+		offset, err = d.jumpOffset(offset)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return offset, nil
+}
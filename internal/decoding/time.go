@@ -0,0 +1,63 @@
+package decoding
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common"
+	mptime "github.com/shamaton/msgpack/v3/time"
+)
+
+// asTime decodes the value at offset into rv of time.Time when it is an
+// integer, a float or a string. found is false for other formats, which
+// are decoded as usual.
+func (d *decoder) asTime(rv reflect.Value, offset int) (int, bool, error) {
+	code, _, err := d.readSize1(offset)
+	if err != nil {
+		return 0, false, nil
+	}
+
+	var t time.Time
+	switch {
+	case d.isCodeInt(code), d.isCodeUint(code):
+		v, o, err := d.asInt(offset, reflect.Int64)
+		if err != nil {
+			return 0, true, err
+		}
+		t, offset = common.TimeFromInt(v, d.formats.Time), o
+
+	case code == def.Float32, code == def.Float64:
+		v, o, err := d.asFloat64(offset, reflect.Float64)
+		if err != nil {
+			return 0, true, err
+		}
+		t, err = common.TimeFromFloat(v)
+		if err != nil {
+			return 0, true, err
+		}
+		offset = o
+
+	case d.isCodeString(code):
+		v, o, err := d.asString(offset, reflect.String)
+		if err != nil {
+			return 0, true, err
+		}
+		// the string keeps its own offset
+		t, err = common.TimeFromString(v)
+		if err != nil {
+			return 0, true, err
+		}
+		rv.Set(reflect.ValueOf(t))
+		return o, true, nil
+
+	default:
+		return 0, false, nil
+	}
+
+	if !mptime.DecodedAsLocal() {
+		t = t.UTC()
+	}
+	rv.Set(reflect.ValueOf(t))
+	return offset, true, nil
+}
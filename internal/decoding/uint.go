@@ -27,7 +27,7 @@ func (d *decoder) asUint(offset int, k reflect.Kind) (uint64, int, error) {
 		if err != nil {
 			return 0, 0, err
 		}
-		v, err := decodingutil.Uint64FromInt64(decodingutil.Int64FromInt8Byte(b), k)
+		v, err := d.narrowing.UintFromInt(decodingutil.Int64FromInt8Byte(b), k)
 		if err != nil {
 			return 0, 0, err
 		}
@@ -47,7 +47,7 @@ func (d *decoder) asUint(offset int, k reflect.Kind) (uint64, int, error) {
 		if err != nil {
 			return 0, 0, err
 		}
-		v, err := decodingutil.Uint64FromInt64(decodingutil.Int64FromInt8Byte(b), k)
+		v, err := d.narrowing.UintFromInt(decodingutil.Int64FromInt8Byte(b), k)
 		if err != nil {
 			return 0, 0, err
 		}
@@ -68,7 +68,7 @@ func (d *decoder) asUint(offset int, k reflect.Kind) (uint64, int, error) {
 		if err != nil {
 			return 0, 0, err
 		}
-		v, err := decodingutil.Uint64FromInt64(decodingutil.Int64FromInt16Bits(binary.BigEndian.Uint16(bs)), k)
+		v, err := d.narrowing.UintFromInt(decodingutil.Int64FromInt16Bits(binary.BigEndian.Uint16(bs)), k)
 		if err != nil {
 			return 0, 0, err
 		}
@@ -89,7 +89,7 @@ func (d *decoder) asUint(offset int, k reflect.Kind) (uint64, int, error) {
 		if err != nil {
 			return 0, 0, err
 		}
-		v, err := decodingutil.Uint64FromInt64(decodingutil.Int64FromInt32Bits(binary.BigEndian.Uint32(bs)), k)
+		v, err := d.narrowing.UintFromInt(decodingutil.Int64FromInt32Bits(binary.BigEndian.Uint32(bs)), k)
 		if err != nil {
 			return 0, 0, err
 		}
@@ -109,7 +109,7 @@ func (d *decoder) asUint(offset int, k reflect.Kind) (uint64, int, error) {
 		if err != nil {
 			return 0, 0, err
 		}
-		v, err := decodingutil.Uint64FromInt64(decodingutil.Int64FromInt64Bits(binary.BigEndian.Uint64(bs)), k)
+		v, err := d.narrowing.UintFromInt(decodingutil.Int64FromInt64Bits(binary.BigEndian.Uint64(bs)), k)
 		if err != nil {
 			return 0, 0, err
 		}
@@ -0,0 +1,149 @@
+package decoding
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common/decodingutil"
+)
+
+func (d *decoder) isCodeUint(code byte) bool {
+	return d.isPositiveFixNum(code) ||
+		code == def.Uint8 || code == def.Uint16 || code == def.Uint32 || code == def.Uint64
+}
+
+func (d *decoder) isCodeInt(code byte) bool {
+	return d.isNegativeFixNum(code) ||
+		code == def.Int8 || code == def.Int16 || code == def.Int32 || code == def.Int64
+}
+
+// asWeak decodes the value at offset into rv by the WeakTypes rules when
+// its format does not match the kind of rv. found is false when no rule
+// applies, and the value is decoded as usual.
+func (d *decoder) asWeak(rv reflect.Value, offset int) (int, bool, error) {
+	code, _, err := d.readSize1(offset)
+	if err != nil {
+		return 0, false, nil
+	}
+
+	k := rv.Kind()
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !d.isCodeString(code) {
+			return 0, false, nil
+		}
+		s, o, err := d.asString(offset, k)
+		if err != nil {
+			return 0, true, err
+		}
+		v, err := decodingutil.ParseInt(s, k)
+		if err != nil {
+			return 0, true, err
+		}
+		v, err = d.narrowing.Int(v, k)
+		if err != nil {
+			return 0, true, err
+		}
+		rv.SetInt(v)
+		return o, true, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !d.isCodeString(code) {
+			return 0, false, nil
+		}
+		s, o, err := d.asString(offset, k)
+		if err != nil {
+			return 0, true, err
+		}
+		v, err := decodingutil.ParseUint(s, k)
+		if err != nil {
+			return 0, true, err
+		}
+		v, err = d.narrowing.Uint(v, k)
+		if err != nil {
+			return 0, true, err
+		}
+		rv.SetUint(v)
+		return o, true, nil
+
+	case reflect.Float32, reflect.Float64:
+		if !d.isCodeString(code) {
+			return 0, false, nil
+		}
+		s, o, err := d.asString(offset, k)
+		if err != nil {
+			return 0, true, err
+		}
+		v, err := decodingutil.ParseFloat(s, k)
+		if err != nil {
+			return 0, true, err
+		}
+		rv.SetFloat(v)
+		return o, true, nil
+
+	case reflect.String:
+		var s string
+		var o int
+		switch {
+		case d.isCodeUint(code):
+			v, o2, err := d.asUint(offset, k)
+			if err != nil {
+				return 0, true, err
+			}
+			s, o = strconv.FormatUint(v, 10), o2
+		case d.isCodeInt(code):
+			v, o2, err := d.asInt(offset, k)
+			if err != nil {
+				return 0, true, err
+			}
+			s, o = strconv.FormatInt(v, 10), o2
+		case code == def.Float32:
+			v, o2, err := d.asFloat32(offset, k)
+			if err != nil {
+				return 0, true, err
+			}
+			s, o = strconv.FormatFloat(float64(v), 'g', -1, 32), o2
+		case code == def.Float64:
+			v, o2, err := d.asFloat64(offset, k)
+			if err != nil {
+				return 0, true, err
+			}
+			s, o = strconv.FormatFloat(v, 'g', -1, 64), o2
+		default:
+			return 0, false, nil
+		}
+		rv.SetString(s)
+		return o, true, nil
+
+	case reflect.Bool:
+		if !d.isCodeUint(code) && !d.isCodeInt(code) {
+			return 0, false, nil
+		}
+		v, o, err := d.asInt(offset, k)
+		if err != nil {
+			return 0, true, err
+		}
+		b, err := decodingutil.BoolFromInt64(v, k)
+		if err != nil {
+			return 0, true, err
+		}
+		rv.SetBool(b)
+		return o, true, nil
+
+	case reflect.Slice:
+		// byte slices keep the bin and string formats
+		if rv.Type().Elem().Kind() == reflect.Uint8 || d.isCodeNil(code) ||
+			d.isFixSlice(code) || code == def.Array16 || code == def.Array32 {
+			return 0, false, nil
+		}
+		sli := reflect.MakeSlice(rv.Type(), 1, 1)
+		o, err := d.decode(sli.Index(0), offset)
+		if err != nil {
+			return 0, true, err
+		}
+		rv.Set(sli)
+		return o, true, nil
+	}
+	return 0, false, nil
+}
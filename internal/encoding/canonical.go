@@ -0,0 +1,49 @@
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/shamaton/msgpack/v3/def"
+)
+
+// sortMapKeys orders keys by their encoded bytes so that canonical output
+// does not depend on the map iteration order.
+func (e *encoder) sortMapKeys(keys []reflect.Value) error {
+	encoded := make([][]byte, len(keys))
+	for i, k := range keys {
+		sub := *e
+		sub.d, sub.mk, sub.mv, sub.extBytes = nil, nil, nil, nil
+		size, err := sub.calcSize(k)
+		if err != nil {
+			return err
+		}
+		sub.d = make([]byte, size)
+		sub.create(k, 0)
+		encoded[i] = sub.d
+	}
+
+	sort.Sort(encodedKeys{keys: keys, encoded: encoded})
+	for i := 1; i < len(encoded); i++ {
+		if bytes.Equal(encoded[i-1], encoded[i]) {
+			return fmt.Errorf("%w: duplicate map key %v", def.ErrNotCanonical, keys[i])
+		}
+	}
+	return nil
+}
+
+type encodedKeys struct {
+	keys    []reflect.Value
+	encoded [][]byte
+}
+
+func (s encodedKeys) Len() int { return len(s.keys) }
+
+func (s encodedKeys) Less(i, j int) bool { return bytes.Compare(s.encoded[i], s.encoded[j]) < 0 }
+
+func (s encodedKeys) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.encoded[i], s.encoded[j] = s.encoded[j], s.encoded[i]
+}
@@ -4,22 +4,46 @@ import (
 	"fmt"
 	"math"
 	"reflect"
+	"time"
 
 	"github.com/shamaton/msgpack/v3/def"
 	"github.com/shamaton/msgpack/v3/internal/common"
 )
 
 type encoder struct {
-	d       []byte
-	asArray bool
+	d              []byte
+	asArray        bool
+	canonical      bool
+	compactFloats  bool
+	floatAsInteger bool
+	fixedWidthInts bool
+	cycles         common.Cycles
+	formats        common.Formats
 	common.Common
 	mk map[uintptr][]reflect.Value
 	mv map[uintptr][]reflect.Value
+	// extBytes holds the values encoded by calcExtCoder until create
+	// writes them
+	extBytes [][]byte
 }
 
 // Encode returns the MessagePack-encoded byte array of v.
 func Encode(v interface{}, asArray bool) (b []byte, err error) {
-	e := encoder{asArray: asArray}
+	return EncodeWithOption(v, common.EncodeOption{AsArray: asArray})
+}
+
+// EncodeWithOption returns the MessagePack-encoded byte array of v
+// according to opt.
+func EncodeWithOption(v interface{}, opt common.EncodeOption) (b []byte, err error) {
+	e := encoder{
+		asArray:        opt.AsArray,
+		canonical:      opt.Canonical,
+		compactFloats:  opt.CompactFloats,
+		floatAsInteger: opt.FloatAsInt,
+		fixedWidthInts: opt.FixedWidthInts && !opt.Canonical,
+		cycles:         opt.Cycles(),
+		formats:        common.Formats{Time: opt.TimeFormat, Duration: opt.DurationFormat},
+	}
 	/*
 		defer func() {
 			e := recover()
@@ -39,7 +63,7 @@ func Encode(v interface{}, asArray bool) (b []byte, err error) {
 	}
 	size, err := e.calcSize(rv)
 	if err != nil {
-		return nil, err
+		return nil, common.RootPath(common.EncodeError(err, rv), rv.Type())
 	}
 
 	e.d = make([]byte, size)
@@ -63,20 +87,35 @@ func Encode(v interface{}, asArray bool) (b []byte, err error) {
 //}
 
 func (e *encoder) calcSize(rv reflect.Value) (int, error) {
+	if extTypeCoders != nil && rv.IsValid() && !(rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		if c, ok := extTypeCoders[rv.Type()]; ok {
+			return e.calcExtCoder(c, rv)
+		}
+	}
+
 	switch rv.Kind() {
 	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
+		if e.fixedWidthInts {
+			return e.calcFixedUint(rv.Kind()), nil
+		}
 		v := rv.Uint()
 		return e.calcUint(v), nil
 
 	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
+		if e.formats.Duration != common.DurationFormatNanos && rv.Type() == common.DurationType {
+			return e.calcSize(common.DurationValue(time.Duration(rv.Int()), e.formats.Duration))
+		}
+		if e.fixedWidthInts {
+			return e.calcFixedInt(rv.Kind()), nil
+		}
 		v := rv.Int()
 		return e.calcInt(int64(v)), nil
 
 	case reflect.Float32:
-		return e.calcFloat32(0), nil
+		return e.calcFloat32(rv.Float()), nil
 
 	case reflect.Float64:
-		return e.calcFloat64(0), nil
+		return e.calcFloat64(rv.Float()), nil
 
 	case reflect.String:
 		return e.calcString(rv.String()), nil
@@ -103,8 +142,10 @@ func (e *encoder) calcSize(rv reflect.Value) (int, error) {
 			return size, nil
 		}
 
-		if size, find := e.calcFixedSlice(rv); find {
-			return size, nil
+		if !e.fixedWidthInts {
+			if size, find := e.calcFixedSlice(rv); find {
+				return size, nil
+			}
 		}
 
 		// func
@@ -122,14 +163,19 @@ func (e *encoder) calcSize(rv reflect.Value) (int, error) {
 			return 0, err
 		}
 
+		if err = e.cycles.Enter(rv); err != nil {
+			return 0, err
+		}
 		// objects size
 		for i := 0; i < l; i++ {
 			s, err := f(rv.Index(i))
 			if err != nil {
-				return 0, err
+				err = common.EncodeError(err, rv.Index(i))
+				return 0, common.ErrorPath(err, common.IndexPath(i))
 			}
 			size += s
 		}
+		e.cycles.Leave(rv)
 		return size, nil
 
 	case reflect.Array:
@@ -161,7 +207,8 @@ func (e *encoder) calcSize(rv reflect.Value) (int, error) {
 		for i := 0; i < l; i++ {
 			s, err := f(rv.Index(i))
 			if err != nil {
-				return 0, err
+				err = common.EncodeError(err, rv.Index(i))
+				return 0, common.ErrorPath(err, common.IndexPath(i))
 			}
 			size += s
 		}
@@ -172,10 +219,15 @@ func (e *encoder) calcSize(rv reflect.Value) (int, error) {
 			return def.Byte1, nil
 		}
 
-		if size, find := e.calcFixedMap(rv); find {
-			return size, nil
+		if !e.canonical && !e.fixedWidthInts {
+			if size, find := e.calcFixedMap(rv); find {
+				return size, nil
+			}
 		}
 
+		if err := e.cycles.Enter(rv); err != nil {
+			return 0, err
+		}
 		if e.mk == nil {
 			e.mk = map[uintptr][]reflect.Value{}
 			e.mv = map[uintptr][]reflect.Value{}
@@ -186,6 +238,11 @@ func (e *encoder) calcSize(rv reflect.Value) (int, error) {
 		if err != nil {
 			return 0, err
 		}
+		if e.canonical {
+			if err = e.sortMapKeys(keys); err != nil {
+				return 0, err
+			}
+		}
 
 		// key-value
 		mv := make([]reflect.Value, len(keys))
@@ -193,18 +250,20 @@ func (e *encoder) calcSize(rv reflect.Value) (int, error) {
 		for _, k := range keys {
 			keySize, err := e.calcSize(k)
 			if err != nil {
-				return 0, err
+				return 0, common.EncodeError(err, k)
 			}
 			value := rv.MapIndex(k)
 			valueSize, err := e.calcSize(value)
 			if err != nil {
-				return 0, err
+				err = common.EncodeError(err, value)
+				return 0, common.ErrorPath(err, common.KeyPath(k))
 			}
 			size += keySize + valueSize
 			mv[i] = value
 			i++
 		}
 		e.mk[rv.Pointer()], e.mv[rv.Pointer()] = keys, mv
+		e.cycles.Leave(rv)
 		return size, nil
 
 	case reflect.Struct:
@@ -218,10 +277,14 @@ func (e *encoder) calcSize(rv reflect.Value) (int, error) {
 		if rv.IsNil() {
 			return def.Byte1, nil
 		}
+		if err := e.cycles.Enter(rv); err != nil {
+			return 0, err
+		}
 		size, err := e.calcSize(rv.Elem())
 		if err != nil {
 			return 0, err
 		}
+		e.cycles.Leave(rv)
 		return size, nil
 
 	case reflect.Interface:
@@ -253,13 +316,28 @@ func (e *encoder) calcLength(l int) (int, error) {
 }
 
 func (e *encoder) create(rv reflect.Value, offset int) int {
+	if extTypeCoders != nil && rv.IsValid() && !(rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		if c, ok := extTypeCoders[rv.Type()]; ok {
+			return e.writeExtCoder(c, rv, offset)
+		}
+	}
+
 	switch rv.Kind() {
 	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
 		v := rv.Uint()
+		if e.fixedWidthInts {
+			return e.writeFixedUint(v, rv.Kind(), offset)
+		}
 		offset = e.writeUint(v, offset)
 
 	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
 		v := rv.Int()
+		if e.formats.Duration != common.DurationFormatNanos && rv.Type() == common.DurationType {
+			return e.create(common.DurationValue(time.Duration(v), e.formats.Duration), offset)
+		}
+		if e.fixedWidthInts {
+			return e.writeFixedInt(v, rv.Kind(), offset)
+		}
 		offset = e.writeInt(v, offset)
 
 	case reflect.Float32:
@@ -292,8 +370,10 @@ func (e *encoder) create(rv reflect.Value, offset int) int {
 			return offset
 		}
 
-		if offset, find := e.writeFixedSlice(rv, offset); find {
-			return offset
+		if !e.fixedWidthInts {
+			if offset, find := e.writeFixedSlice(rv, offset); find {
+				return offset
+			}
 		}
 
 		// func
@@ -349,8 +429,10 @@ func (e *encoder) create(rv reflect.Value, offset int) int {
 		l := rv.Len()
 		offset = e.writeMapLength(l, offset)
 
-		if offset, find := e.writeFixedMap(rv, offset); find {
-			return offset
+		if !e.canonical && !e.fixedWidthInts {
+			if offset, find := e.writeFixedMap(rv, offset); find {
+				return offset
+			}
 		}
 
 		// key-value
@@ -4,12 +4,16 @@ import (
 	"reflect"
 
 	"github.com/shamaton/msgpack/v3/ext"
+	"github.com/shamaton/msgpack/v3/internal/common"
 	"github.com/shamaton/msgpack/v3/time"
 )
 
 var (
 	extCoderMap = map[reflect.Type]ext.Encoder{time.Encoder.Type(): time.Encoder}
-	extCoders   = []ext.Encoder{time.Encoder}
+	// extTypeCoders holds the encoders of named types other than structs,
+	// which are looked up for every value
+	extTypeCoders map[reflect.Type]ext.Encoder
+	extCoders     = []ext.Encoder{time.Encoder}
 )
 
 // AddExtEncoder adds encoders for extension types.
@@ -42,11 +46,61 @@ func RemoveExtEncoder(f ext.Encoder) {
 
 func updateExtCoders() {
 	extCoders = make([]ext.Encoder, len(extCoderMap))
+	extTypeCoders = nil
 	i := 0
 	for k := range extCoderMap {
 		extCoders[i] = extCoderMap[k]
 		i++
+		if common.IsExtType(k) {
+			if extTypeCoders == nil {
+				extTypeCoders = map[reflect.Type]ext.Encoder{}
+			}
+			extTypeCoders[k] = extCoderMap[k]
+		}
+	}
+}
+
+// calcExtCoder returns the size of rv encoded by c. The bytes of
+// encoders that return them are kept for writeExtCoder.
+func (e *encoder) calcExtCoder(c ext.Encoder, rv reflect.Value) (int, error) {
+	b, ok := c.(common.ExtBytesEncoder)
+	if !ok {
+		return c.CalcByteSize(rv)
+	}
+	data, err := b.EncodeExt(rv)
+	if err != nil {
+		return 0, err
+	}
+	e.extBytes = append(e.extBytes, data)
+	return len(data), nil
+}
+
+// writeExtCoder writes rv encoded by c, which create reaches in the same
+// order as calcSize.
+func (e *encoder) writeExtCoder(c ext.Encoder, rv reflect.Value, offset int) int {
+	if _, ok := c.(common.ExtBytesEncoder); !ok {
+		return c.WriteToBytes(rv, offset, &e.d)
 	}
+	data := e.extBytes[0]
+	e.extBytes = e.extBytes[1:]
+	return e.setBytes(data, offset)
+}
+
+func (e *encoder) calcExt(x common.Ext) (int, error) {
+	var buf [6]byte
+	h, err := common.ExtHeader(buf[:0], x.Type, len(x.Data))
+	if err != nil {
+		return 0, err
+	}
+	return len(h) + len(x.Data), nil
+}
+
+func (e *encoder) writeExt(x common.Ext, offset int) int {
+	// the length is checked by calcExt
+	var buf [6]byte
+	h, _ := common.ExtHeader(buf[:0], x.Type, len(x.Data))
+	offset = e.setBytes(h, offset)
+	return e.setBytes(x.Data, offset)
 }
 
 /*
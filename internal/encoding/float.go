@@ -4,23 +4,58 @@ import (
 	"math"
 
 	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common/encodingutil"
 )
 
-func (e *encoder) calcFloat32(_ float64) int {
+func (e *encoder) calcFloat32(v float64) int {
+	if i, ok := e.floatAsInt(v); ok {
+		return e.calcInt(i)
+	}
 	return def.Byte1 + def.Byte4
 }
 
-func (e *encoder) calcFloat64(_ float64) int {
+func (e *encoder) calcFloat64(v float64) int {
+	if e.isFloat64AsFloat32(v) {
+		return e.calcFloat32(v)
+	}
+	if i, ok := e.floatAsInt(v); ok {
+		return e.calcInt(i)
+	}
 	return def.Byte1 + def.Byte8
 }
 
+// isFloat64AsFloat32 reports whether a float64 is written in float32 format.
+func (e *encoder) isFloat64AsFloat32(v float64) bool {
+	if e.canonical && math.IsNaN(v) {
+		return true
+	}
+	return (e.canonical || e.compactFloats) && encodingutil.IsFloat32Exact(v)
+}
+
+// floatAsInt returns the int value of a float written in int format.
+func (e *encoder) floatAsInt(v float64) (int64, bool) {
+	if !e.floatAsInteger {
+		return 0, false
+	}
+	return encodingutil.FloatAsInt(v)
+}
+
 func (e *encoder) writeFloat32(v float64, offset int) int {
+	if i, ok := e.floatAsInt(v); ok {
+		return e.writeInt(i, offset)
+	}
 	offset = e.setByte1Int(def.Float32, offset)
-	offset = e.setByte4Uint64(uint64(math.Float32bits(float32(v))), offset)
+	offset = e.setByte4Uint64(uint64(encodingutil.Float32Bits(v, e.canonical)), offset)
 	return offset
 }
 
 func (e *encoder) writeFloat64(v float64, offset int) int {
+	if e.isFloat64AsFloat32(v) {
+		return e.writeFloat32(v, offset)
+	}
+	if i, ok := e.floatAsInt(v); ok {
+		return e.writeInt(i, offset)
+	}
 	offset = e.setByte1Int(def.Float64, offset)
 	offset = e.setByte8Uint64(math.Float64bits(v), offset)
 	return offset
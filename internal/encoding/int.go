@@ -2,8 +2,10 @@ package encoding
 
 import (
 	"math"
+	"reflect"
 
 	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common/encodingutil"
 )
 
 func (e *encoder) isNegativeFixInt64(v int64) bool {
@@ -46,3 +48,26 @@ func (e *encoder) writeInt(v int64, offset int) int {
 	}
 	return offset
 }
+
+// calcFixedInt returns the size of an int written in the format of kind k.
+func (e *encoder) calcFixedInt(k reflect.Kind) int {
+	_, size := encodingutil.IntFormat(k)
+	return def.Byte1 + size
+}
+
+// writeFixedInt writes v in the int format that matches the width of k.
+func (e *encoder) writeFixedInt(v int64, k reflect.Kind, offset int) int {
+	code, size := encodingutil.IntFormat(k)
+	offset = e.setByte1Int(int(code), offset)
+	switch size {
+	case def.Byte1:
+		offset = e.setByte1Int64(v, offset)
+	case def.Byte2:
+		offset = e.setByte2Int64(v, offset)
+	case def.Byte4:
+		offset = e.setByte4Int64(v, offset)
+	default:
+		offset = e.setByte8Int64(v, offset)
+	}
+	return offset
+}
@@ -38,17 +38,17 @@ func (e *encoder) calcFixedMap(rv reflect.Value) (int, bool) {
 
 	case map[string]float32:
 		size, _ := e.calcLength(len(m))
-		for k := range m {
+		for k, v := range m {
 			size += e.calcString(k)
-			size += e.calcFloat32(0)
+			size += e.calcFloat32(float64(v))
 		}
 		return size, true
 
 	case map[string]float64:
 		size, _ := e.calcLength(len(m))
-		for k := range m {
+		for k, v := range m {
 			size += e.calcString(k)
-			size += e.calcFloat64(0)
+			size += e.calcFloat64(v)
 		}
 		return size, true
 
@@ -4,9 +4,11 @@ import (
 	"math"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/shamaton/msgpack/v3/def"
 	"github.com/shamaton/msgpack/v3/internal/common"
+	"github.com/shamaton/msgpack/v3/internal/common/encodingutil"
 )
 
 type structCache struct {
@@ -15,6 +17,9 @@ type structCache struct {
 	omits  []bool
 	noOmit bool
 
+	// canonicalOrder is the field order of map format in canonical mode
+	canonicalOrder []int
+
 	// fast path detection
 	hasEmbedded bool
 
@@ -25,6 +30,9 @@ type structCache struct {
 	indexes   [][]int   // field path (support for embedded structs)
 	omitPaths [][][]int // embedded omitempty parent paths
 
+	// formats holds the formats set by field tags
+	formats map[int]common.FormatTags
+
 	common.Common
 }
 
@@ -63,9 +71,14 @@ func shouldOmitByParent(rv reflect.Value, omitPaths [][]int) bool {
 }
 
 func (e *encoder) getStructCalc(typ reflect.Type) structCalcFunc {
+	if typ == common.ExtType || typ == common.TimeType && e.formats.Time != common.TimeFormatExt {
+		return e.calcStruct
+	}
 	for j := range extCoders {
 		if extCoders[j].Type() == typ {
-			return extCoders[j].CalcByteSize
+			return func(rv reflect.Value) (int, error) {
+				return e.calcExtCoder(extCoders[j], rv)
+			}
 		}
 	}
 	if e.asArray {
@@ -80,9 +93,16 @@ func (e *encoder) calcStruct(rv reflect.Value) (int, error) {
 	//	return size, nil
 	//}
 
+	if rv.Type() == common.ExtType {
+		return e.calcExt(rv.Interface().(common.Ext))
+	}
+	if rv.Type() == common.TimeType && e.formats.Time != common.TimeFormatExt {
+		return e.calcSize(common.TimeValue(rv.Interface().(time.Time), e.formats.Time))
+	}
+
 	for i := range extCoders {
 		if extCoders[i].Type() == rv.Type() {
-			return extCoders[i].CalcByteSize(rv)
+			return e.calcExtCoder(extCoders[i], rv)
 		}
 	}
 
@@ -126,6 +146,8 @@ func (e *encoder) calcStructArray(rv reflect.Value) (int, error) {
 			}
 		}
 		c.noOmit = omitCount == 0
+		c.formats = common.FieldFormats(fields)
+		c.canonicalOrder = encodingutil.CanonicalStringOrder(c.names)
 		cachemap.Store(t, c)
 	} else {
 		c = cache.(*structCache)
@@ -140,18 +162,24 @@ func (e *encoder) calcStructArray(rv reflect.Value) (int, error) {
 			if shouldOmitByParent(rv, c.omitPaths[i]) || !ok {
 				fieldValue = reflect.Value{}
 			}
+			f := e.useFormats(c, i)
 			size, err := e.calcSize(fieldValue)
+			e.formats = f
 			if err != nil {
-				return 0, err
+				err = common.EncodeError(err, fieldValue)
+				return 0, common.ErrorPath(err, common.FieldPath(t, c.indexes[i]))
 			}
 			ret += size
 		}
 	} else {
 		numFields = len(c.simpleIndexes)
 		for i := 0; i < numFields; i++ {
+			f := e.useFormats(c, i)
 			size, err := e.calcSize(rv.Field(c.simpleIndexes[i]))
+			e.formats = f
 			if err != nil {
-				return 0, err
+				err = common.EncodeError(err, rv.Field(c.simpleIndexes[i]))
+				return 0, common.ErrorPath(err, common.FieldPath(t, c.simpleIndexes[i:i+1]))
 			}
 			ret += size
 		}
@@ -200,6 +228,8 @@ func (e *encoder) calcStructMap(rv reflect.Value) (int, error) {
 			}
 		}
 		c.noOmit = omitCount == 0
+		c.formats = common.FieldFormats(fields)
+		c.canonicalOrder = encodingutil.CanonicalStringOrder(c.names)
 		cachemap.Store(t, c)
 	} else {
 		c = cache.(*structCache)
@@ -212,9 +242,12 @@ func (e *encoder) calcStructMap(rv reflect.Value) (int, error) {
 			if shouldOmitByParent(rv, c.omitPaths[i]) || !ok {
 				continue
 			}
+			f := e.useFormats(c, i)
 			size, err := e.calcSizeWithOmitEmpty(fieldValue, c.names[i], c.omits[i])
+			e.formats = f
 			if err != nil {
-				return 0, err
+				err = common.EncodeError(err, fieldValue)
+				return 0, common.ErrorPath(err, common.FieldPath(t, c.indexes[i]))
 			}
 			ret += size
 			if size > 0 {
@@ -223,9 +256,12 @@ func (e *encoder) calcStructMap(rv reflect.Value) (int, error) {
 		}
 	} else {
 		for i := 0; i < len(c.simpleIndexes); i++ {
+			f := e.useFormats(c, i)
 			size, err := e.calcSizeWithOmitEmpty(rv.Field(c.simpleIndexes[i]), c.names[i], c.omits[i])
+			e.formats = f
 			if err != nil {
-				return 0, err
+				err = common.EncodeError(err, rv.Field(c.simpleIndexes[i]))
+				return 0, common.ErrorPath(err, common.FieldPath(t, c.simpleIndexes[i:i+1]))
 			}
 			ret += size
 			if size > 0 {
@@ -258,10 +294,13 @@ func (e *encoder) calcSizeWithOmitEmpty(rv reflect.Value, name string, omit bool
 }
 
 func (e *encoder) getStructWriter(typ reflect.Type) structWriteFunc {
+	if typ == common.ExtType || typ == common.TimeType && e.formats.Time != common.TimeFormatExt {
+		return e.writeStruct
+	}
 	for i := range extCoders {
 		if extCoders[i].Type() == typ {
 			return func(rv reflect.Value, offset int) int {
-				return extCoders[i].WriteToBytes(rv, offset, &e.d)
+				return e.writeExtCoder(extCoders[i], rv, offset)
 			}
 		}
 	}
@@ -279,9 +318,16 @@ func (e *encoder) writeStruct(rv reflect.Value, offset int) int {
 		}
 	*/
 
+	if rv.Type() == common.ExtType {
+		return e.writeExt(rv.Interface().(common.Ext), offset)
+	}
+	if rv.Type() == common.TimeType && e.formats.Time != common.TimeFormatExt {
+		return e.create(common.TimeValue(rv.Interface().(time.Time), e.formats.Time), offset)
+	}
+
 	for i := range extCoders {
 		if extCoders[i].Type() == rv.Type() {
-			return extCoders[i].WriteToBytes(rv, offset, &e.d)
+			return e.writeExtCoder(extCoders[i], rv, offset)
 		}
 	}
 
@@ -319,11 +365,15 @@ func (e *encoder) writeStructArray(rv reflect.Value, offset int) int {
 			if shouldOmitByParent(rv, c.omitPaths[i]) || !ok {
 				fieldValue = reflect.Value{}
 			}
+			f := e.useFormats(c, i)
 			offset = e.create(fieldValue, offset)
+			e.formats = f
 		}
 	} else {
 		for i := 0; i < num; i++ {
+			f := e.useFormats(c, i)
 			offset = e.create(rv.Field(c.simpleIndexes[i]), offset)
+			e.formats = f
 		}
 	}
 	return offset
@@ -367,25 +417,49 @@ func (e *encoder) writeStructMap(rv reflect.Value, offset int) int {
 
 	if c.hasEmbedded {
 		num := len(c.indexes)
-		for i := 0; i < num; i++ {
+		for j := 0; j < num; j++ {
+			i := e.fieldIndex(c, j)
 			fieldValue, ok := getFieldByPath(rv, c.indexes[i])
 			if shouldOmitByParent(rv, c.omitPaths[i]) || !ok {
 				continue
 			}
 			if c.noOmit || !c.omits[i] || !fieldValue.IsZero() {
 				offset = e.writeString(c.names[i], offset)
+				f := e.useFormats(c, i)
 				offset = e.create(fieldValue, offset)
+				e.formats = f
 			}
 		}
 	} else {
 		num := len(c.simpleIndexes)
-		for i := 0; i < num; i++ {
+		for j := 0; j < num; j++ {
+			i := e.fieldIndex(c, j)
 			fieldValue := rv.Field(c.simpleIndexes[i])
 			if c.noOmit || !c.omits[i] || !fieldValue.IsZero() {
 				offset = e.writeString(c.names[i], offset)
+				f := e.useFormats(c, i)
 				offset = e.create(fieldValue, offset)
+				e.formats = f
 			}
 		}
 	}
 	return offset
 }
+
+// useFormats applies the format tags of the i-th field of c and
+// returns the formats to restore afterwards.
+func (e *encoder) useFormats(c *structCache, i int) common.Formats {
+	f := e.formats
+	if t, ok := c.formats[i]; ok {
+		e.formats = t.Apply(f)
+	}
+	return f
+}
+
+// fieldIndex returns the cache index of the j-th field written in map format.
+func (e *encoder) fieldIndex(c *structCache, j int) int {
+	if e.canonical {
+		return c.canonicalOrder[j]
+	}
+	return j
+}
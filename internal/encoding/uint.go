@@ -2,8 +2,10 @@ package encoding
 
 import (
 	"math"
+	"reflect"
 
 	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common/encodingutil"
 )
 
 func (e *encoder) calcUint(v uint64) int {
@@ -38,3 +40,26 @@ func (e *encoder) writeUint(v uint64, offset int) int {
 	}
 	return offset
 }
+
+// calcFixedUint returns the size of a uint written in the format of kind k.
+func (e *encoder) calcFixedUint(k reflect.Kind) int {
+	_, size := encodingutil.UintFormat(k)
+	return def.Byte1 + size
+}
+
+// writeFixedUint writes v in the uint format that matches the width of k.
+func (e *encoder) writeFixedUint(v uint64, k reflect.Kind, offset int) int {
+	code, size := encodingutil.UintFormat(k)
+	offset = e.setByte1Int(int(code), offset)
+	switch size {
+	case def.Byte1:
+		offset = e.setByte1Uint64(v, offset)
+	case def.Byte2:
+		offset = e.setByte2Uint64(v, offset)
+	case def.Byte4:
+		offset = e.setByte4Uint64(v, offset)
+	default:
+		offset = e.setByte8Uint64(v, offset)
+	}
+	return offset
+}
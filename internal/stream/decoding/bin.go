@@ -23,7 +23,7 @@ func (d *decoder) asBinWithCode(code byte, k reflect.Kind) ([]byte, error) {
 			return emptyBytes, err
 		}
 		// avoid common buffer reference
-		return d.copySizeN(int(l))
+		return d.copyBinN(int(l))
 
 	case def.Bin16:
 		bs, err := d.readSize2()
@@ -31,7 +31,7 @@ func (d *decoder) asBinWithCode(code byte, k reflect.Kind) ([]byte, error) {
 			return emptyBytes, err
 		}
 		// avoid common buffer reference
-		return d.copySizeN(int(binary.BigEndian.Uint16(bs)))
+		return d.copyBinN(int(binary.BigEndian.Uint16(bs)))
 
 	case def.Bin32:
 		bs, err := d.readSize4()
@@ -39,7 +39,7 @@ func (d *decoder) asBinWithCode(code byte, k reflect.Kind) ([]byte, error) {
 			return emptyBytes, err
 		}
 		// avoid common buffer reference
-		return d.copySizeN(int(binary.BigEndian.Uint32(bs)))
+		return d.copyBinN(int(binary.BigEndian.Uint32(bs)))
 	}
 
 	return emptyBytes, d.errorTemplate(code, k)
@@ -50,6 +50,14 @@ func (d *decoder) asBinStringWithCode(code byte, k reflect.Kind) (string, error)
 	return string(bs), err
 }
 
+// copyBinN checks the bin length limit before copying n bytes.
+func (d *decoder) copyBinN(n int) ([]byte, error) {
+	if err := d.limits.CheckBinLen(n); err != nil {
+		return emptyBytes, err
+	}
+	return d.copySizeN(n)
+}
+
 func (d *decoder) copySizeN(n int) ([]byte, error) {
 	bs, err := d.readSizeN(n)
 	if err != nil {
@@ -1,25 +1,51 @@
 package decoding
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"reflect"
 
 	"github.com/shamaton/msgpack/v3/def"
 	"github.com/shamaton/msgpack/v3/internal/common"
-	"github.com/shamaton/msgpack/v3/internal/common/decodingutil"
 )
 
 type decoder struct {
-	r       io.Reader
-	asArray bool
-	buf     *common.Buffer
+	r         io.Reader
+	asArray   bool
+	interner  *common.Interner
+	depth     int
+	maxDepth  int
+	limits    common.Limits
+	weakTypes bool
+	narrowing common.Narrowing
+	formats   common.Formats
+	// fixedTypes allows slices and maps of basic types to be decoded in
+	// bulk, without cancellation checks
+	fixedTypes bool
+	total      int
+	ctx        context.Context
+	done       <-chan struct{}
+	buf        *common.Buffer
 	common.Common
 }
 
 // Decode analyzes the MessagePack-encoded data and stores
 // the result into the pointer of v.
 func Decode(r io.Reader, v interface{}, asArray bool) error {
+	return DecodeWithOption(r, v, common.DecodeOption{AsArray: asArray})
+}
+
+// DecodeWithOption analyzes the MessagePack-encoded data and stores
+// the result into the pointer of v according to opt.
+func DecodeWithOption(r io.Reader, v interface{}, opt common.DecodeOption) error {
+	return DecodeContext(context.Background(), r, v, opt)
+}
+
+// DecodeContext analyzes the MessagePack-encoded data and stores
+// the result into the pointer of v according to opt. It stops between
+// the elements of arrays and maps once ctx is done.
+func DecodeContext(ctx context.Context, r io.Reader, v interface{}, opt common.DecodeOption) error {
 	if r == nil {
 		return def.ErrNoData
 	}
@@ -31,24 +57,65 @@ func Decode(r io.Reader, v interface{}, asArray bool) error {
 	rv = rv.Elem()
 
 	d := decoder{
-		r:       r,
-		buf:     common.GetBuffer(),
-		asArray: asArray,
+		r:         r,
+		buf:       common.GetBuffer(),
+		asArray:   opt.AsArray,
+		interner:  opt.Interner,
+		maxDepth:  opt.Depth(),
+		limits:    opt.Limits,
+		weakTypes: opt.WeakTypes,
+		narrowing: opt.Narrowing,
+		formats:   common.Formats{Time: opt.TimeFormat, Duration: opt.DurationFormat},
+		ctx:       ctx,
+		done:      ctx.Done(),
+
+		fixedTypes: opt.FixedTypes() && ctx.Done() == nil,
 	}
 	err := d.decode(rv)
 	common.PutBuffer(d.buf)
-	return err
+	if err != nil {
+		return common.RootPath(err, rv.Type())
+	}
+	return nil
 }
 
 func (d *decoder) decode(rv reflect.Value) error {
+	pos := d.total
 	code, err := d.readSize1()
 	if err != nil {
-		return err
+		return common.DecodeError(err, pos, 0, rv.Type())
 	}
 	return d.decodeWithCode(code, rv)
 }
 
+// decodeWithCode decodes the value whose code was just read.
 func (d *decoder) decodeWithCode(code byte, rv reflect.Value) error {
+	pos := d.total - 1
+	if err := d.decodeValue(code, rv); err != nil {
+		return common.DecodeError(err, pos, code, rv.Type())
+	}
+	return nil
+}
+
+func (d *decoder) decodeValue(code byte, rv reflect.Value) error {
+	if extTypeCoders != nil {
+		if c, ok := extTypeCoders[rv.Type()]; ok {
+			if found, err := d.asExtType(c, code, rv); found {
+				return err
+			}
+		}
+	}
+	if d.formats.Duration != common.DurationFormatNanos && rv.Type() == common.DurationType {
+		if found, err := d.asDuration(code, rv); found {
+			return err
+		}
+	}
+	if d.weakTypes {
+		if found, err := d.asWeak(code, rv); found {
+			return err
+		}
+	}
+
 	k := rv.Kind()
 	switch k {
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
@@ -56,7 +123,7 @@ func (d *decoder) decodeWithCode(code byte, rv reflect.Value) error {
 		if err != nil {
 			return err
 		}
-		v, err = decodingutil.IntValueForKind(v, k)
+		v, err = d.narrowing.Int(v, k)
 		if err != nil {
 			return err
 		}
@@ -67,7 +134,7 @@ func (d *decoder) decodeWithCode(code byte, rv reflect.Value) error {
 		if err != nil {
 			return err
 		}
-		v, err = decodingutil.UintValueForKind(v, k)
+		v, err = d.narrowing.Uint(v, k)
 		if err != nil {
 			return err
 		}
@@ -144,31 +211,30 @@ func (d *decoder) decodeWithCode(code byte, rv reflect.Value) error {
 			return err
 		}
 
-		// check fixed type
-		found, err := d.asFixedSlice(rv, l)
-		if err != nil {
-			return err
+		if d.fixedTypes {
+			found, err := d.asFixedSlice(rv, l)
+			if err != nil {
+				return err
+			}
+			if found {
+				return nil
+			}
 		}
-		if found {
-			return nil
+
+		if err = d.enter(); err != nil {
+			return err
 		}
+		defer d.leave()
 
 		// create slice dynamically
 		tmpSlice := reflect.MakeSlice(rv.Type(), l, l)
 		for i := 0; i < l; i++ {
+			if err = d.contextErr(); err != nil {
+				return err
+			}
 			v := tmpSlice.Index(i)
-			if v.Kind() == reflect.Struct {
-				structCode, err := d.readSize1()
-				if err != nil {
-					return err
-				}
-				if err = d.setStruct(structCode, v, k); err != nil {
-					return err
-				}
-			} else {
-				if err = d.decode(v); err != nil {
-					return err
-				}
+			if err = d.decode(v); err != nil {
+				return common.ErrorPath(err, common.IndexPath(i))
 			}
 		}
 		rv.Set(tmpSlice)
@@ -235,11 +301,19 @@ func (d *decoder) decodeWithCode(code byte, rv reflect.Value) error {
 			return fmt.Errorf("%v len is %d, but msgpack has %d elements, %w", rv.Type(), rv.Len(), l, def.ErrNotMatchArrayElement)
 		}
 
+		if err = d.enter(); err != nil {
+			return err
+		}
+		defer d.leave()
+
 		// create array dynamically
 		for i := 0; i < l; i++ {
+			if err = d.contextErr(); err != nil {
+				return err
+			}
 			err = d.decode(rv.Index(i))
 			if err != nil {
-				return err
+				return common.ErrorPath(err, common.IndexPath(i))
 			}
 		}
 
@@ -255,14 +329,20 @@ func (d *decoder) decodeWithCode(code byte, rv reflect.Value) error {
 			return err
 		}
 
-		// check fixed type
-		found, err := d.asFixedMap(rv, l)
-		if err != nil {
-			return err
+		if d.fixedTypes {
+			found, err := d.asFixedMap(rv, l)
+			if err != nil {
+				return err
+			}
+			if found {
+				return nil
+			}
 		}
-		if found {
-			return nil
+
+		if err = d.enter(); err != nil {
+			return err
 		}
+		defer d.leave()
 
 		// create dynamically
 		key := rv.Type().Key()
@@ -271,6 +351,9 @@ func (d *decoder) decodeWithCode(code byte, rv reflect.Value) error {
 			rv.Set(reflect.MakeMapWithSize(rv.Type(), l))
 		}
 		for i := 0; i < l; i++ {
+			if err = d.contextErr(); err != nil {
+				return err
+			}
 			k := reflect.New(key).Elem()
 			v := reflect.New(value).Elem()
 			err = d.decode(k)
@@ -279,13 +362,26 @@ func (d *decoder) decodeWithCode(code byte, rv reflect.Value) error {
 			}
 			err = d.decode(v)
 			if err != nil {
-				return err
+				return common.ErrorPath(err, common.KeyPath(k))
 			}
 
 			rv.SetMapIndex(k, v)
 		}
 
 	case reflect.Struct:
+		if rv.Type() == common.ExtType && common.IsExtCode(code) {
+			typ, data, err := d.readIfExtType(code)
+			if err != nil {
+				return err
+			}
+			rv.Set(reflect.ValueOf(rawExt(typ, data)))
+			return nil
+		}
+		if rv.Type() == common.TimeType && d.formats.Time != common.TimeFormatExt {
+			if found, err := d.asTime(code, rv); found {
+				return err
+			}
+		}
 		err := d.setStruct(code, rv, k)
 		if err != nil {
 			return err
@@ -329,6 +425,28 @@ func (d *decoder) decodeWithCode(code byte, rv reflect.Value) error {
 	return nil
 }
 
+// enter increases the nesting depth before the elements of a container
+// are decoded and fails when it would exceed the limit, leaving the depth
+// as it is. Zero means no limit.
+func (d *decoder) enter() error {
+	if d.depth >= d.maxDepth && d.maxDepth > 0 {
+		return fmt.Errorf("%w: %d", def.ErrMaxDepthExceeded, d.maxDepth)
+	}
+	d.depth++
+	return nil
+}
+
+// leave decreases the nesting depth after a successful enter, which
+// callers defer so that errors do not leave the depth raised.
+func (d *decoder) leave() {
+	d.depth--
+}
+
+// contextErr returns the context error once decoding is cancelled.
+func (d *decoder) contextErr() error {
+	return common.ContextErr(d.ctx, d.done)
+}
+
 func (d *decoder) errorTemplate(code byte, k reflect.Kind) error {
 	return fmt.Errorf("%w %x decoding as %v", def.ErrCanNotDecode, code, k)
 }
@@ -0,0 +1,53 @@
+package decoding
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common"
+)
+
+// asDuration decodes the value of code into rv of time.Duration when it
+// is an integer, a float or a string. found is false for other formats,
+// which are decoded as usual.
+func (d *decoder) asDuration(code byte, rv reflect.Value) (bool, error) {
+	var v time.Duration
+	switch {
+	case d.isCodeInt(code), d.isCodeUint(code):
+		i, err := d.asIntWithCode(code, reflect.Int64)
+		if err != nil {
+			return true, err
+		}
+		v, err = common.DurationFromInt(i, d.formats.Duration)
+		if err != nil {
+			return true, err
+		}
+
+	case code == def.Float32, code == def.Float64:
+		f, err := d.asFloat64WithCode(code, reflect.Float64)
+		if err != nil {
+			return true, err
+		}
+		v, err = common.DurationFromFloat(f)
+		if err != nil {
+			return true, err
+		}
+
+	case d.isCodeString(code):
+		s, err := d.asStringWithCode(code, reflect.String)
+		if err != nil {
+			return true, err
+		}
+		v, err = common.DurationFromString(s)
+		if err != nil {
+			return true, err
+		}
+
+	default:
+		return false, nil
+	}
+
+	rv.SetInt(int64(v))
+	return true, nil
+}
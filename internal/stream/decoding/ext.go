@@ -2,22 +2,36 @@ package decoding
 
 import (
 	"encoding/binary"
+	"reflect"
 
 	"github.com/shamaton/msgpack/v3/def"
 	"github.com/shamaton/msgpack/v3/ext"
+	"github.com/shamaton/msgpack/v3/internal/common"
 	"github.com/shamaton/msgpack/v3/internal/common/decodingutil"
 	"github.com/shamaton/msgpack/v3/time"
 )
 
 var (
-	extCoderMap = map[int8]ext.StreamDecoder{time.StreamDecoder.Code(): time.StreamDecoder}
-	extCoders   = []ext.StreamDecoder{time.StreamDecoder}
+	timeDecoder = ext.AdaptStreamDecoder(time.StreamDecoder)
+	extCoderMap = map[int8]ext.StreamDecoderV2{timeDecoder.Code(): timeDecoder}
+	// extTypeCoders holds the decoders of named types other than structs,
+	// which are looked up for every value
+	extTypeCoders map[reflect.Type]ext.StreamDecoderV2
+	extCoders     = []ext.StreamDecoderV2{timeDecoder}
+	// extIntCoders holds the decoders that accept plain integers
+	extIntCoders []ext.StreamDecoderV2
 )
 
 // AddExtDecoder adds decoders for extension types.
 func AddExtDecoder(f ext.StreamDecoder) {
+	AddExtDecoderV2(ext.AdaptStreamDecoder(f))
+}
+
+// AddExtDecoderV2 adds decoders for extension types that decode into
+// their destination.
+func AddExtDecoderV2(f ext.StreamDecoderV2) {
 	// ignore time
-	if f.Code() == time.Decoder.Code() {
+	if f.Code() == timeDecoder.Code() {
 		return
 	}
 
@@ -30,8 +44,14 @@ func AddExtDecoder(f ext.StreamDecoder) {
 
 // RemoveExtDecoder removes decoders for extension types.
 func RemoveExtDecoder(f ext.StreamDecoder) {
+	RemoveExtDecoderV2(ext.AdaptStreamDecoder(f))
+}
+
+// RemoveExtDecoderV2 removes decoders for extension types that decode
+// into their destination.
+func RemoveExtDecoderV2(f ext.StreamDecoderV2) {
 	// ignore time
-	if f.Code() == time.Decoder.Code() {
+	if f.Code() == timeDecoder.Code() {
 		return
 	}
 
@@ -42,12 +62,90 @@ func RemoveExtDecoder(f ext.StreamDecoder) {
 	}
 }
 
+// AddExtTypeDecoder adds the decoder of t, a named type other than a struct,
+// which is used for every value decoded into t.
+func AddExtTypeDecoder(t reflect.Type, f ext.StreamDecoderV2) {
+	if extTypeCoders == nil {
+		extTypeCoders = map[reflect.Type]ext.StreamDecoderV2{}
+	}
+	extTypeCoders[t] = f
+}
+
+// RemoveExtTypeDecoder removes the decoder of t.
+func RemoveExtTypeDecoder(t reflect.Type) {
+	delete(extTypeCoders, t)
+	if len(extTypeCoders) == 0 {
+		extTypeCoders = nil
+	}
+}
+
+// asExtType decodes the value of code into rv with c, the decoder of the
+// type of rv. Extensions, and integers when c accepts them, are offered
+// to c, and found is false when an integer is not of its type, which is
+// decoded as usual.
+func (d *decoder) asExtType(c ext.StreamDecoderV2, code byte, rv reflect.Value) (bool, error) {
+	innerType, data, err := d.readIfExtType(code)
+	if err != nil {
+		return true, err
+	}
+	if !common.IsExtCode(code) {
+		if !ext.AcceptsInts(c) || !d.isCodeInt(code) && !d.isCodeUint(code) {
+			return false, nil
+		}
+		n := intDataLen(code)
+		if !c.IsType(code, 0, n) {
+			return false, nil
+		}
+		if data, err = d.readIntData(n); err != nil {
+			return true, err
+		}
+	} else if !c.IsType(code, innerType, len(data)) {
+		// the extension has been read and can't be decoded as usual
+		return true, d.errorTemplate(code, rv.Kind())
+	}
+
+	return true, c.DecodeValue(code, data, rv)
+}
+
+// rawExt returns the extension of type typ with data as it is encoded.
+// data is copied, as it is held by the read buffers.
+func rawExt(typ int8, data []byte) common.Ext {
+	return common.Ext{Type: typ, Data: append([]byte{}, data...)}
+}
+
+// intDataLen returns the number of bytes after the code of an integer.
+func intDataLen(code byte) int {
+	switch code {
+	case def.Uint8, def.Int8:
+		return def.Byte1
+	case def.Uint16, def.Int16:
+		return def.Byte2
+	case def.Uint32, def.Int32:
+		return def.Byte4
+	case def.Uint64, def.Int64:
+		return def.Byte8
+	}
+	return 0
+}
+
+// readIntData reads the n bytes after the code of an integer.
+func (d *decoder) readIntData(n int) ([]byte, error) {
+	if n == 0 {
+		return nil, nil
+	}
+	return d.readSizeN(n)
+}
+
 func updateExtCoders() {
-	extCoders = make([]ext.StreamDecoder, len(extCoderMap))
+	extCoders = make([]ext.StreamDecoderV2, len(extCoderMap))
+	extIntCoders = nil
 	i := 0
 	for k := range extCoderMap {
 		extCoders[i] = extCoderMap[k]
 		i++
+		if ext.AcceptsInts(extCoderMap[k]) {
+			extIntCoders = append(extIntCoders, extCoderMap[k])
+		}
 	}
 }
 
@@ -82,7 +82,7 @@ func (d *decoder) asIntWithCode(code byte, k reflect.Kind) (int64, error) {
 		if err != nil {
 			return 0, err
 		}
-		return decodingutil.Int64FromUint64(binary.BigEndian.Uint64(bs), k)
+		return d.narrowing.IntFromUint(binary.BigEndian.Uint64(bs), k)
 
 	case code == def.Int64:
 		bs, err := d.readSize8()
@@ -96,14 +96,14 @@ func (d *decoder) asIntWithCode(code byte, k reflect.Kind) (int64, error) {
 		if err != nil {
 			return 0, err
 		}
-		return decodingutil.Int64FromFloat64(float64(v), k)
+		return d.narrowing.IntFromFloat(float64(v), k)
 
 	case code == def.Float64:
 		v, err := d.asFloat64WithCode(code, k)
 		if err != nil {
 			return 0, err
 		}
-		return decodingutil.Int64FromFloat64(v, k)
+		return d.narrowing.IntFromFloat(v, k)
 
 	case code == def.Nil:
 		return 0, nil
@@ -5,15 +5,23 @@ import (
 	"reflect"
 
 	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common"
 	"github.com/shamaton/msgpack/v3/internal/common/decodingutil"
 )
 
+var interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+
 func (d *decoder) asInterface(k reflect.Kind) (interface{}, error) {
+	pos := d.total
 	code, err := d.readSize1()
 	if err != nil {
-		return nil, err
+		return nil, common.DecodeError(err, pos, 0, interfaceType)
 	}
-	return d.asInterfaceWithCode(code, k)
+	v, err := d.asInterfaceWithCode(code, k)
+	if err != nil {
+		return nil, common.DecodeError(err, pos, code, interfaceType)
+	}
+	return v, nil
 }
 
 func (d *decoder) asInterfaceWithCode(code byte, k reflect.Kind) (interface{}, error) {
@@ -111,11 +119,18 @@ func (d *decoder) asInterfaceWithCode(code byte, k reflect.Kind) (interface{}, e
 			return nil, err
 		}
 
+		if err = d.enter(); err != nil {
+			return nil, err
+		}
+		defer d.leave()
 		v := make([]interface{}, l)
 		for i := 0; i < l; i++ {
+			if err := d.contextErr(); err != nil {
+				return nil, err
+			}
 			vv, err := d.asInterface(k)
 			if err != nil {
-				return nil, err
+				return nil, common.ErrorPath(err, common.IndexPath(i))
 			}
 			v[i] = vv
 		}
@@ -127,23 +142,31 @@ func (d *decoder) asInterfaceWithCode(code byte, k reflect.Kind) (interface{}, e
 			return nil, err
 		}
 
+		if err = d.enter(); err != nil {
+			return nil, err
+		}
+		defer d.leave()
 		v := make(map[interface{}]interface{}, l)
 		for i := 0; i < l; i++ {
+			if err := d.contextErr(); err != nil {
+				return nil, err
+			}
+			pos := d.total
 			keyCode, err := d.readSize1()
 			if err != nil {
-				return 0, err
+				return 0, common.DecodeError(err, pos, 0, interfaceType)
 			}
 
 			if err := d.canSetAsMapKey(keyCode); err != nil {
-				return nil, err
+				return nil, common.DecodeError(err, pos, keyCode, interfaceType)
 			}
 			key, err := d.asInterfaceWithCode(keyCode, k)
 			if err != nil {
-				return nil, err
+				return nil, common.DecodeError(err, pos, keyCode, interfaceType)
 			}
 			value, err := d.asInterface(k)
 			if err != nil {
-				return nil, err
+				return nil, common.ErrorPath(err, common.KeyPath(reflect.ValueOf(key)))
 			}
 			v[key] = value
 		}
@@ -157,13 +180,17 @@ func (d *decoder) asInterfaceWithCode(code byte, k reflect.Kind) (interface{}, e
 	}
 	for i := range extCoders {
 		if extCoders[i].IsType(code, extInnerType, len(extData)) {
-			v, err := extCoders[i].ToValue(code, extData, k)
-			if err != nil {
+			rv := reflect.New(interfaceType).Elem()
+			if err := extCoders[i].DecodeValue(code, extData, rv); err != nil {
 				return nil, err
 			}
-			return v, nil
+			return rv.Interface(), nil
 		}
 	}
+	if common.IsExtCode(code) {
+		// the type has no decoder, so it is passed through as it is
+		return rawExt(extInnerType, extData), nil
+	}
 	return nil, d.errorTemplate(code, k)
 }
 
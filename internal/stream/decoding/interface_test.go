@@ -8,6 +8,7 @@ import (
 
 	"github.com/shamaton/msgpack/v3/def"
 	"github.com/shamaton/msgpack/v3/ext"
+	"github.com/shamaton/msgpack/v3/internal/common"
 )
 
 func Test_asInterface(t *testing.T) {
@@ -202,11 +203,19 @@ func Test_asInterfaceWithCode(t *testing.T) {
 			MethodAsWithCode: method,
 		},
 		{
-			Name:             "Unexpected",
+			Name:             "Ext.raw",
 			Code:             def.Fixext1,
 			Data:             []byte{4, 0},
 			ReadCount:        2,
-			Error:            def.ErrCanNotDecode,
+			Expected:         common.Ext{Type: 4, Data: []byte{0}},
+			MethodAsWithCode: method,
+		},
+		{
+			Name:             "Ext.raw.empty",
+			Code:             def.Ext8,
+			Data:             []byte{0, 0xfc},
+			ReadCount:        2,
+			Expected:         common.Ext{Type: -4, Data: []byte{}},
 			MethodAsWithCode: method,
 		},
 	}
@@ -60,24 +60,29 @@ func (d *decoder) isFixMap(v byte) bool {
 }
 
 func (d *decoder) mapLength(code byte, k reflect.Kind) (int, error) {
+	var l int
 	switch {
 	case d.isFixMap(code):
-		return int(code - def.FixMap), nil
+		l = int(code - def.FixMap)
 	case code == def.Map16:
 		bs, err := d.readSize2()
 		if err != nil {
 			return 0, err
 		}
-		return int(binary.BigEndian.Uint16(bs)), nil
+		l = int(binary.BigEndian.Uint16(bs))
 	case code == def.Map32:
 		bs, err := d.readSize4()
 		if err != nil {
 			return 0, err
 		}
-		return int(binary.BigEndian.Uint32(bs)), nil
+		l = int(binary.BigEndian.Uint32(bs))
+	default:
+		return 0, d.errorTemplate(code, k)
 	}
-
-	return 0, d.errorTemplate(code, k)
+	if err := d.limits.CheckMapLen(l); err != nil {
+		return 0, err
+	}
+	return l, nil
 }
 
 func (d *decoder) asFixedMap(rv reflect.Value, l int) (bool, error) {
@@ -1,6 +1,9 @@
 package decoding
 
 func (d *decoder) readSize1() (byte, error) {
+	if err := d.count(1); err != nil {
+		return 0, err
+	}
 	if _, err := d.r.Read(d.buf.B1); err != nil {
 		return 0, err
 	}
@@ -8,6 +11,9 @@ func (d *decoder) readSize1() (byte, error) {
 }
 
 func (d *decoder) readSize2() ([]byte, error) {
+	if err := d.count(2); err != nil {
+		return emptyBytes, err
+	}
 	if _, err := d.r.Read(d.buf.B2); err != nil {
 		return emptyBytes, err
 	}
@@ -15,6 +21,9 @@ func (d *decoder) readSize2() ([]byte, error) {
 }
 
 func (d *decoder) readSize4() ([]byte, error) {
+	if err := d.count(4); err != nil {
+		return emptyBytes, err
+	}
 	if _, err := d.r.Read(d.buf.B4); err != nil {
 		return emptyBytes, err
 	}
@@ -22,6 +31,9 @@ func (d *decoder) readSize4() ([]byte, error) {
 }
 
 func (d *decoder) readSize8() ([]byte, error) {
+	if err := d.count(8); err != nil {
+		return emptyBytes, err
+	}
 	if _, err := d.r.Read(d.buf.B8); err != nil {
 		return emptyBytes, err
 	}
@@ -29,6 +41,9 @@ func (d *decoder) readSize8() ([]byte, error) {
 }
 
 func (d *decoder) readSize16() ([]byte, error) {
+	if err := d.count(16); err != nil {
+		return emptyBytes, err
+	}
 	if _, err := d.r.Read(d.buf.B16); err != nil {
 		return emptyBytes, err
 	}
@@ -36,6 +51,13 @@ func (d *decoder) readSize16() ([]byte, error) {
 }
 
 func (d *decoder) readSizeN(n int) ([]byte, error) {
+	if err := d.count(n); err != nil {
+		return emptyBytes, err
+	}
+	// readers may report the end of the data for an empty read
+	if n == 0 {
+		return emptyBytes, nil
+	}
 	var b []byte
 	if n <= len(d.buf.Data) {
 		b = d.buf.Data[:n]
@@ -48,3 +70,10 @@ func (d *decoder) readSizeN(n int) ([]byte, error) {
 	}
 	return b, nil
 }
+
+// count adds n to the number of bytes read and fails before reading
+// past the total size limit.
+func (d *decoder) count(n int) error {
+	d.total += n
+	return d.limits.CheckTotalBytes(d.total)
+}
@@ -0,0 +1,103 @@
+package decoding
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common"
+)
+
+// Sequence decodes the elements of a top-level array or map one by one,
+// so that the whole container does not have to be held in memory.
+type Sequence struct {
+	d decoder
+
+	// isMap is set by MapLength, after which Next decodes keys and
+	// values in turn
+	isMap bool
+	// n counts the values decoded by Next
+	n   int
+	key reflect.Value
+}
+
+// NewSequence returns a Sequence that reads from r.
+// Close must be called after use.
+func NewSequence(r io.Reader, asArray bool) *Sequence {
+	return &Sequence{d: decoder{
+		r:        r,
+		buf:      common.GetBuffer(),
+		asArray:  asArray,
+		maxDepth: common.DefaultMaxDepth,
+	}}
+}
+
+// ArrayLength reads an array header and returns the number of elements.
+// nil is treated as an empty array.
+func (s *Sequence) ArrayLength() (int, error) {
+	if s.d.r == nil {
+		return 0, def.ErrNoData
+	}
+	code, err := s.d.readSize1()
+	if err != nil {
+		return 0, err
+	}
+	if code == def.Nil {
+		return 0, nil
+	}
+	return s.d.sliceLength(code, reflect.Slice)
+}
+
+// MapLength reads a map header and returns the number of key-value pairs.
+// nil is treated as an empty map.
+func (s *Sequence) MapLength() (int, error) {
+	if s.d.r == nil {
+		return 0, def.ErrNoData
+	}
+	code, err := s.d.readSize1()
+	if err != nil {
+		return 0, err
+	}
+	s.isMap = true
+	if code == def.Nil {
+		return 0, nil
+	}
+	return s.d.mapLength(code, reflect.Map)
+}
+
+// Next decodes the next value into the pointer of v. The path of an
+// error starts with the index of the element, or the key of the map
+// value, as if the container were decoded at once.
+func (s *Sequence) Next(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr {
+		return fmt.Errorf("%w. v.(type): %T", def.ErrReceiverNotPointer, v)
+	}
+	n := s.n
+	s.n++
+	err := s.d.decode(rv.Elem())
+	switch {
+	case !s.isMap:
+		if err != nil {
+			err = common.ErrorPath(err, common.IndexPath(n))
+		}
+	case n%2 == 0:
+		s.key = rv.Elem()
+	case err != nil:
+		err = common.ErrorPath(err, common.KeyPath(s.key))
+	}
+	if err != nil {
+		// the container is unnamed
+		return common.RootPath(err, nil)
+	}
+	return nil
+}
+
+// Close releases the buffer of the Sequence.
+func (s *Sequence) Close() {
+	if s.d.buf != nil {
+		common.PutBuffer(s.d.buf)
+		s.d.buf = nil
+	}
+}
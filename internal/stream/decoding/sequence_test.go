@@ -0,0 +1,62 @@
+package decoding
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/shamaton/msgpack/v3/def"
+	tu "github.com/shamaton/msgpack/v3/internal/common/testutil"
+)
+
+func TestSequence(t *testing.T) {
+	t.Run("Array", func(t *testing.T) {
+		s := NewSequence(bytes.NewReader([]byte{0x92, 0x01, 0xa1, 'a'}), false)
+		defer s.Close()
+
+		l, err := s.ArrayLength()
+		tu.NoError(t, err)
+		tu.Equal(t, l, 2)
+
+		var i int
+		tu.NoError(t, s.Next(&i))
+		tu.Equal(t, i, 1)
+		var str string
+		tu.NoError(t, s.Next(&str))
+		tu.Equal(t, str, "a")
+
+		tu.IsError(t, s.Next(&i), io.EOF)
+	})
+	t.Run("Map", func(t *testing.T) {
+		s := NewSequence(bytes.NewReader([]byte{0x81, 0xa1, 'a', 0x01}), false)
+		defer s.Close()
+
+		l, err := s.MapLength()
+		tu.NoError(t, err)
+		tu.Equal(t, l, 1)
+	})
+	t.Run("Nil", func(t *testing.T) {
+		s := NewSequence(bytes.NewReader([]byte{def.Nil, def.Nil}), false)
+		defer s.Close()
+
+		l, err := s.ArrayLength()
+		tu.NoError(t, err)
+		tu.Equal(t, l, 0)
+		l, err = s.MapLength()
+		tu.NoError(t, err)
+		tu.Equal(t, l, 0)
+	})
+	t.Run("Error", func(t *testing.T) {
+		s := NewSequence(bytes.NewReader([]byte{0x80}), false)
+		defer s.Close()
+
+		_, err := s.ArrayLength()
+		tu.IsError(t, err, def.ErrCanNotDecode)
+
+		_, err = NewSequence(nil, false).MapLength()
+		tu.IsError(t, err, def.ErrNoData)
+
+		var i int
+		tu.IsError(t, s.Next(i), def.ErrReceiverNotPointer)
+	})
+}
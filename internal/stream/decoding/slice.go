@@ -34,23 +34,29 @@ func (d *decoder) isFixSlice(v byte) bool {
 }
 
 func (d *decoder) sliceLength(code byte, k reflect.Kind) (int, error) {
+	var l int
 	switch {
 	case d.isFixSlice(code):
-		return int(code - def.FixArray), nil
+		l = int(code - def.FixArray)
 	case code == def.Array16:
 		bs, err := d.readSize2()
 		if err != nil {
 			return 0, err
 		}
-		return int(binary.BigEndian.Uint16(bs)), nil
+		l = int(binary.BigEndian.Uint16(bs))
 	case code == def.Array32:
 		bs, err := d.readSize4()
 		if err != nil {
 			return 0, err
 		}
-		return int(binary.BigEndian.Uint32(bs)), nil
+		l = int(binary.BigEndian.Uint32(bs))
+	default:
+		return 0, d.errorTemplate(code, k)
 	}
-	return 0, d.errorTemplate(code, k)
+	if err := d.limits.CheckArrayLen(l); err != nil {
+		return 0, err
+	}
+	return l, nil
 }
 
 func (d *decoder) asFixedSlice(rv reflect.Value, l int) (bool, error) {
@@ -21,31 +21,36 @@ func (d *decoder) isFixString(v byte) bool {
 }
 
 func (d *decoder) stringByteLength(code byte, k reflect.Kind) (int, error) {
+	var l int
 	if def.FixStr <= code && code <= def.FixStr+0x1f {
-		l := int(code - def.FixStr)
-		return l, nil
+		l = int(code - def.FixStr)
 	} else if code == def.Str8 {
 		b, err := d.readSize1()
 		if err != nil {
 			return 0, err
 		}
-		return int(b), nil
+		l = int(b)
 	} else if code == def.Str16 {
 		b, err := d.readSize2()
 		if err != nil {
 			return 0, err
 		}
-		return int(binary.BigEndian.Uint16(b)), nil
+		l = int(binary.BigEndian.Uint16(b))
 	} else if code == def.Str32 {
 		b, err := d.readSize4()
 		if err != nil {
 			return 0, err
 		}
-		return int(binary.BigEndian.Uint32(b)), nil
+		l = int(binary.BigEndian.Uint32(b))
 	} else if code == def.Nil {
 		return 0, nil
+	} else {
+		return 0, d.errorTemplate(code, k)
 	}
-	return 0, d.errorTemplate(code, k)
+	if err := d.limits.CheckStringLen(l); err != nil {
+		return 0, err
+	}
+	return l, nil
 }
 
 func (d *decoder) asString(k reflect.Kind) (string, error) {
@@ -61,7 +66,7 @@ func (d *decoder) asStringWithCode(code byte, k reflect.Kind) (string, error) {
 	if err != nil {
 		return emptyString, err
 	}
-	return string(bs), nil
+	return d.string(bs), nil
 }
 
 func (d *decoder) asStringByte(k reflect.Kind) ([]byte, error) {
@@ -89,3 +94,11 @@ func (d *decoder) asStringByteByLength(l int, _ reflect.Kind) ([]byte, error) {
 	// avoid common buffer reference
 	return d.copySizeN(l)
 }
+
+// string returns bs as a string, sharing the strings held by the interner.
+func (d *decoder) string(bs []byte) string {
+	if d.interner != nil {
+		return d.interner.String(bs)
+	}
+	return string(bs)
+}
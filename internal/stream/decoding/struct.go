@@ -2,10 +2,12 @@ package decoding
 
 import (
 	"encoding/binary"
+	"errors"
 	"reflect"
 	"sync"
 
 	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common"
 )
 
 type structCacheTypeMap struct {
@@ -19,6 +21,9 @@ type structCacheTypeMap struct {
 
 	// embedded path (hasEmbedded == true): path-based access
 	indexes [][]int // field path (support for embedded structs)
+
+	// formats holds the formats set by field tags
+	formats map[int]common.FormatTags
 }
 
 type structCacheTypeArray struct {
@@ -30,6 +35,9 @@ type structCacheTypeArray struct {
 
 	// embedded path (hasEmbedded == true): path-based access
 	indexes [][]int // field path (support for embedded structs)
+
+	// formats holds the formats set by field tags
+	formats map[int]common.FormatTags
 }
 
 // struct cache map
@@ -58,34 +66,73 @@ func getFieldByPath(rv reflect.Value, path []int, allowAlloc bool) (reflect.Valu
 	return rv, true
 }
 
+// setStructFromInt offers a plain integer to the ext decoders that accept
+// them, for types such as big.Int. innerType is 0 and data holds the bytes after code.
+// found is false when no decoder accepts it and nothing was read.
+func (d *decoder) setStructFromInt(code byte, rv reflect.Value, k reflect.Kind) (bool, error) {
+	n := intDataLen(code)
+	var data []byte
+	read := false
+	for i := range extIntCoders {
+		if !extIntCoders[i].IsType(code, 0, n) {
+			continue
+		}
+		if !read {
+			var err error
+			if data, err = d.readIntData(n); err != nil {
+				return true, err
+			}
+			read = true
+		}
+		err := extIntCoders[i].DecodeValue(code, data, rv)
+		// another decoder may decode into the type of rv
+		if errors.Is(err, def.ErrExtTypeMismatch) {
+			continue
+		}
+		return true, err
+	}
+	if read {
+		return true, d.errorTemplate(code, k)
+	}
+	return false, nil
+}
+
 func (d *decoder) setStruct(code byte, rv reflect.Value, k reflect.Kind) error {
 	if len(extCoders) > 0 {
 		innerType, data, err := d.readIfExtType(code)
 		if err != nil {
 			return err
 		}
+		if len(extIntCoders) > 0 && (d.isCodeInt(code) || d.isCodeUint(code)) {
+			if found, err := d.setStructFromInt(code, rv, k); found {
+				return err
+			}
+		}
 		if data != nil {
 			for i := range extCoders {
 				if extCoders[i].IsType(code, innerType, len(data)) {
-					v, err := extCoders[i].ToValue(code, data, k)
-					if err != nil {
-						return err
-					}
-
-					// Validate that the receptacle is of the right value type.
-					if rv.Type() == reflect.TypeOf(v) {
-						rv.Set(reflect.ValueOf(v))
-						return nil
+					err := extCoders[i].DecodeValue(code, data, rv)
+					// another decoder may decode into the type of rv
+					if errors.Is(err, def.ErrExtTypeMismatch) {
+						continue
 					}
+					return err
 				}
 			}
 		}
 	}
 
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+	var err error
 	if d.asArray {
-		return d.setStructFromArray(code, rv, k)
+		err = d.setStructFromArray(code, rv, k)
+	} else {
+		err = d.setStructFromMap(code, rv, k)
 	}
-	return d.setStructFromMap(code, rv, k)
+	return err
 }
 
 func (d *decoder) setStructFromArray(code byte, rv reflect.Value, k reflect.Kind) error {
@@ -119,6 +166,7 @@ func (d *decoder) setStructFromArray(code byte, rv reflect.Value, k reflect.Kind
 				scta.simpleIndexes = append(scta.simpleIndexes, field.Path[0])
 			}
 		}
+		scta.formats = common.FieldFormats(fields)
 		mapSCTA.Store(rv.Type(), scta)
 	} else {
 		scta = cache.(*structCacheTypeArray)
@@ -135,9 +183,11 @@ func (d *decoder) setStructFromArray(code byte, rv reflect.Value, k reflect.Kind
 				allowAlloc := !d.isCodeNil(code)
 				fieldValue, ok := getFieldByPath(rv, scta.indexes[i], allowAlloc)
 				if ok {
+					f := d.useFormats(scta.formats, i)
 					err = d.decodeWithCode(code, fieldValue)
+					d.formats = f
 					if err != nil {
-						return err
+						return common.ErrorPath(err, common.FieldPath(rv.Type(), scta.indexes[i]))
 					}
 				} else if !d.isCodeNil(code) {
 					return d.errorTemplate(code, k)
@@ -152,9 +202,11 @@ func (d *decoder) setStructFromArray(code byte, rv reflect.Value, k reflect.Kind
 	} else {
 		for i := 0; i < l; i++ {
 			if i < len(scta.simpleIndexes) {
+				f := d.useFormats(scta.formats, i)
 				err = d.decode(rv.Field(scta.simpleIndexes[i]))
+				d.formats = f
 				if err != nil {
-					return err
+					return common.ErrorPath(err, common.FieldPath(rv.Type(), scta.simpleIndexes[i:i+1]))
 				}
 			} else {
 				err = d.jumpOffset()
@@ -198,6 +250,7 @@ func (d *decoder) setStructFromMap(code byte, rv reflect.Value, k reflect.Kind)
 				sctm.simpleIndexes = append(sctm.simpleIndexes, field.Path[0])
 			}
 		}
+		sctm.formats = common.FieldFormats(fields)
 		mapSCTM.Store(rv.Type(), sctm)
 	} else {
 		sctm = cache.(*structCacheTypeMap)
@@ -210,7 +263,7 @@ func (d *decoder) setStructFromMap(code byte, rv reflect.Value, k reflect.Kind)
 				return err
 			}
 
-			fieldPath := []int(nil)
+			fieldPath, fieldKey := []int(nil), -1
 			for keyIndex, keyBytes := range sctm.keys {
 				if len(keyBytes) != len(dataKey) {
 					continue
@@ -224,7 +277,7 @@ func (d *decoder) setStructFromMap(code byte, rv reflect.Value, k reflect.Kind)
 					}
 				}
 				if found {
-					fieldPath = sctm.indexes[keyIndex]
+					fieldPath, fieldKey = sctm.indexes[keyIndex], keyIndex
 					break
 				}
 			}
@@ -237,9 +290,11 @@ func (d *decoder) setStructFromMap(code byte, rv reflect.Value, k reflect.Kind)
 				allowAlloc := !d.isCodeNil(code)
 				fieldValue, ok := getFieldByPath(rv, fieldPath, allowAlloc)
 				if ok {
+					f := d.useFormats(sctm.formats, fieldKey)
 					err = d.decodeWithCode(code, fieldValue)
+					d.formats = f
 					if err != nil {
-						return err
+						return common.ErrorPath(err, common.FieldPath(rv.Type(), fieldPath))
 					}
 				} else if !d.isCodeNil(code) {
 					return d.errorTemplate(code, k)
@@ -258,7 +313,7 @@ func (d *decoder) setStructFromMap(code byte, rv reflect.Value, k reflect.Kind)
 				return err
 			}
 
-			fieldIndex := -1
+			fieldIndex, fieldKey := -1, -1
 			for keyIndex, keyBytes := range sctm.keys {
 				if len(keyBytes) != len(dataKey) {
 					continue
@@ -272,15 +327,17 @@ func (d *decoder) setStructFromMap(code byte, rv reflect.Value, k reflect.Kind)
 					}
 				}
 				if found {
-					fieldIndex = sctm.simpleIndexes[keyIndex]
+					fieldIndex, fieldKey = sctm.simpleIndexes[keyIndex], keyIndex
 					break
 				}
 			}
 
 			if fieldIndex >= 0 {
+				f := d.useFormats(sctm.formats, fieldKey)
 				err = d.decode(rv.Field(fieldIndex))
+				d.formats = f
 				if err != nil {
-					return err
+					return common.ErrorPath(err, common.FieldPath(rv.Type(), []int{fieldIndex}))
 				}
 			} else {
 				err = d.jumpOffset()
@@ -293,6 +350,16 @@ func (d *decoder) setStructFromMap(code byte, rv reflect.Value, k reflect.Kind)
 	return nil
 }
 
+// useFormats applies the format tags of the i-th field in formats and
+// returns the formats to restore afterwards.
+func (d *decoder) useFormats(formats map[int]common.FormatTags, i int) common.Formats {
+	f := d.formats
+	if t, ok := formats[i]; ok {
+		d.formats = t.Apply(f)
+	}
+	return f
+}
+
 func (d *decoder) jumpOffset() error {
 	code, err := d.readSize1()
 	if err != nil {
@@ -345,10 +412,8 @@ func (d *decoder) jumpOffset() error {
 
 	case d.isFixSlice(code):
 		l := int(code - def.FixArray)
-		for i := 0; i < l; i++ {
-			if err = d.jumpOffset(); err != nil {
-				return err
-			}
+		if err = d.jumpElements(l); err != nil {
+			return err
 		}
 	case code == def.Array16:
 		bs, err := d.readSize2()
@@ -356,10 +421,8 @@ func (d *decoder) jumpOffset() error {
 			return err
 		}
 		l := int(binary.BigEndian.Uint16(bs))
-		for i := 0; i < l; i++ {
-			if err = d.jumpOffset(); err != nil {
-				return err
-			}
+		if err = d.jumpElements(l); err != nil {
+			return err
 		}
 	case code == def.Array32:
 		bs, err := d.readSize4()
@@ -367,18 +430,14 @@ func (d *decoder) jumpOffset() error {
 			return err
 		}
 		l := int(binary.BigEndian.Uint32(bs))
-		for i := 0; i < l; i++ {
-			if err = d.jumpOffset(); err != nil {
-				return err
-			}
+		if err = d.jumpElements(l); err != nil {
+			return err
 		}
 
 	case d.isFixMap(code):
 		l := int(code - def.FixMap)
-		for i := 0; i < l*2; i++ {
-			if err = d.jumpOffset(); err != nil {
-				return err
-			}
+		if err = d.jumpElements(l * 2); err != nil {
+			return err
 		}
 	case code == def.Map16:
 		bs, err := d.readSize2()
@@ -386,10 +445,8 @@ func (d *decoder) jumpOffset() error {
 			return err
 		}
 		l := int(binary.BigEndian.Uint16(bs))
-		for i := 0; i < l*2; i++ {
-			if err = d.jumpOffset(); err != nil {
-				return err
-			}
+		if err = d.jumpElements(l * 2); err != nil {
+			return err
 		}
 	case code == def.Map32:
 		bs, err := d.readSize4()
@@ -397,10 +454,8 @@ func (d *decoder) jumpOffset() error {
 			return err
 		}
 		l := int(binary.BigEndian.Uint32(bs))
-		for i := 0; i < l*2; i++ {
-			if err = d.jumpOffset(); err != nil {
-				return err
-			}
+		if err = d.jumpElements(l * 2); err != nil {
+			return err
 		}
 
 	case code == def.Fixext1:
@@ -443,3 +498,20 @@ func (d *decoder) jumpOffset() error {
 	}
 	return nil
 }
+
+// jumpElements skips n consecutive values inside a container.
+func (d *decoder) jumpElements(n int) error {
+	if err := d.enter(); err != nil {
+		return err
+	}
+	defer d.leave()
+	for i := 0; i < n; i++ {
+		if err := d.contextErr(); err != nil {
+			return err
+		}
+		if err := d.jumpOffset(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
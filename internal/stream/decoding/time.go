@@ -0,0 +1,57 @@
+package decoding
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common"
+	mptime "github.com/shamaton/msgpack/v3/time"
+)
+
+// asTime decodes the value of code into rv of time.Time when it is an
+// integer, a float or a string. found is false for other formats, which
+// are decoded as usual.
+func (d *decoder) asTime(code byte, rv reflect.Value) (bool, error) {
+	var t time.Time
+	switch {
+	case d.isCodeInt(code), d.isCodeUint(code):
+		v, err := d.asIntWithCode(code, reflect.Int64)
+		if err != nil {
+			return true, err
+		}
+		t = common.TimeFromInt(v, d.formats.Time)
+
+	case code == def.Float32, code == def.Float64:
+		v, err := d.asFloat64WithCode(code, reflect.Float64)
+		if err != nil {
+			return true, err
+		}
+		t, err = common.TimeFromFloat(v)
+		if err != nil {
+			return true, err
+		}
+
+	case d.isCodeString(code):
+		v, err := d.asStringWithCode(code, reflect.String)
+		if err != nil {
+			return true, err
+		}
+		// the string keeps its own offset
+		t, err = common.TimeFromString(v)
+		if err != nil {
+			return true, err
+		}
+		rv.Set(reflect.ValueOf(t))
+		return true, nil
+
+	default:
+		return false, nil
+	}
+
+	if !mptime.DecodedAsLocal() {
+		t = t.UTC()
+	}
+	rv.Set(reflect.ValueOf(t))
+	return true, nil
+}
@@ -22,7 +22,7 @@ func (d *decoder) asUintWithCode(code byte, k reflect.Kind) (uint64, error) {
 		return uint64(code), nil
 
 	case d.isNegativeFixNum(code):
-		return decodingutil.Uint64FromInt64(decodingutil.Int64FromInt8Byte(code), k)
+		return d.narrowing.UintFromInt(decodingutil.Int64FromInt8Byte(code), k)
 
 	case code == def.Uint8:
 		b, err := d.readSize1()
@@ -36,7 +36,7 @@ func (d *decoder) asUintWithCode(code byte, k reflect.Kind) (uint64, error) {
 		if err != nil {
 			return 0, err
 		}
-		return decodingutil.Uint64FromInt64(decodingutil.Int64FromInt8Byte(b), k)
+		return d.narrowing.UintFromInt(decodingutil.Int64FromInt8Byte(b), k)
 
 	case code == def.Uint16:
 		bs, err := d.readSize2()
@@ -51,7 +51,7 @@ func (d *decoder) asUintWithCode(code byte, k reflect.Kind) (uint64, error) {
 		if err != nil {
 			return 0, err
 		}
-		return decodingutil.Uint64FromInt64(decodingutil.Int64FromInt16Bits(binary.BigEndian.Uint16(bs)), k)
+		return d.narrowing.UintFromInt(decodingutil.Int64FromInt16Bits(binary.BigEndian.Uint16(bs)), k)
 
 	case code == def.Uint32:
 		bs, err := d.readSize4()
@@ -66,7 +66,7 @@ func (d *decoder) asUintWithCode(code byte, k reflect.Kind) (uint64, error) {
 		if err != nil {
 			return 0, err
 		}
-		return decodingutil.Uint64FromInt64(decodingutil.Int64FromInt32Bits(binary.BigEndian.Uint32(bs)), k)
+		return d.narrowing.UintFromInt(decodingutil.Int64FromInt32Bits(binary.BigEndian.Uint32(bs)), k)
 
 	case code == def.Uint64:
 		bs, err := d.readSize8()
@@ -80,7 +80,7 @@ func (d *decoder) asUintWithCode(code byte, k reflect.Kind) (uint64, error) {
 		if err != nil {
 			return 0, err
 		}
-		return decodingutil.Uint64FromInt64(decodingutil.Int64FromInt64Bits(binary.BigEndian.Uint64(bs)), k)
+		return d.narrowing.UintFromInt(decodingutil.Int64FromInt64Bits(binary.BigEndian.Uint64(bs)), k)
 
 	case code == def.Nil:
 		return 0, nil
@@ -0,0 +1,143 @@
+package decoding
+
+import (
+	"reflect"
+	"strconv"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common/decodingutil"
+)
+
+func (d *decoder) isCodeUint(code byte) bool {
+	return d.isPositiveFixNum(code) ||
+		code == def.Uint8 || code == def.Uint16 || code == def.Uint32 || code == def.Uint64
+}
+
+func (d *decoder) isCodeInt(code byte) bool {
+	return d.isNegativeFixNum(code) ||
+		code == def.Int8 || code == def.Int16 || code == def.Int32 || code == def.Int64
+}
+
+// asWeak decodes the value of code into rv by the WeakTypes rules when
+// its format does not match the kind of rv. found is false when no rule
+// applies, and the value is decoded as usual.
+func (d *decoder) asWeak(code byte, rv reflect.Value) (bool, error) {
+	k := rv.Kind()
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if !d.isCodeString(code) {
+			return false, nil
+		}
+		s, err := d.asStringWithCode(code, k)
+		if err != nil {
+			return true, err
+		}
+		v, err := decodingutil.ParseInt(s, k)
+		if err != nil {
+			return true, err
+		}
+		v, err = d.narrowing.Int(v, k)
+		if err != nil {
+			return true, err
+		}
+		rv.SetInt(v)
+		return true, nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if !d.isCodeString(code) {
+			return false, nil
+		}
+		s, err := d.asStringWithCode(code, k)
+		if err != nil {
+			return true, err
+		}
+		v, err := decodingutil.ParseUint(s, k)
+		if err != nil {
+			return true, err
+		}
+		v, err = d.narrowing.Uint(v, k)
+		if err != nil {
+			return true, err
+		}
+		rv.SetUint(v)
+		return true, nil
+
+	case reflect.Float32, reflect.Float64:
+		if !d.isCodeString(code) {
+			return false, nil
+		}
+		s, err := d.asStringWithCode(code, k)
+		if err != nil {
+			return true, err
+		}
+		v, err := decodingutil.ParseFloat(s, k)
+		if err != nil {
+			return true, err
+		}
+		rv.SetFloat(v)
+		return true, nil
+
+	case reflect.String:
+		var s string
+		switch {
+		case d.isCodeUint(code):
+			v, err := d.asUintWithCode(code, k)
+			if err != nil {
+				return true, err
+			}
+			s = strconv.FormatUint(v, 10)
+		case d.isCodeInt(code):
+			v, err := d.asIntWithCode(code, k)
+			if err != nil {
+				return true, err
+			}
+			s = strconv.FormatInt(v, 10)
+		case code == def.Float32:
+			v, err := d.asFloat32WithCode(code, k)
+			if err != nil {
+				return true, err
+			}
+			s = strconv.FormatFloat(float64(v), 'g', -1, 32)
+		case code == def.Float64:
+			v, err := d.asFloat64WithCode(code, k)
+			if err != nil {
+				return true, err
+			}
+			s = strconv.FormatFloat(v, 'g', -1, 64)
+		default:
+			return false, nil
+		}
+		rv.SetString(s)
+		return true, nil
+
+	case reflect.Bool:
+		if !d.isCodeUint(code) && !d.isCodeInt(code) {
+			return false, nil
+		}
+		v, err := d.asIntWithCode(code, k)
+		if err != nil {
+			return true, err
+		}
+		b, err := decodingutil.BoolFromInt64(v, k)
+		if err != nil {
+			return true, err
+		}
+		rv.SetBool(b)
+		return true, nil
+
+	case reflect.Slice:
+		// byte slices keep the bin and string formats
+		if rv.Type().Elem().Kind() == reflect.Uint8 || d.isCodeNil(code) ||
+			d.isFixSlice(code) || code == def.Array16 || code == def.Array32 {
+			return false, nil
+		}
+		sli := reflect.MakeSlice(rv.Type(), 1, 1)
+		err := d.decodeWithCode(code, sli.Index(0))
+		if err != nil {
+			return true, err
+		}
+		rv.Set(sli)
+		return true, nil
+	}
+	return false, nil
+}
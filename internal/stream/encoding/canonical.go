@@ -0,0 +1,69 @@
+package encoding
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common"
+)
+
+// writeCanonicalMap writes the key-value pairs of rv ordered by the encoded
+// bytes of the keys. The map length must already be written.
+func (e *encoder) writeCanonicalMap(rv reflect.Value) error {
+	keys := rv.MapKeys()
+	encoded := make([][]byte, len(keys))
+	for i, k := range keys {
+		bs, err := e.encodeKey(k)
+		if err != nil {
+			return err
+		}
+		encoded[i] = bs
+	}
+
+	sort.Sort(encodedKeys{keys: keys, encoded: encoded})
+	for i := range keys {
+		if i > 0 && bytes.Equal(encoded[i-1], encoded[i]) {
+			return fmt.Errorf("%w: duplicate map key %v", def.ErrNotCanonical, keys[i])
+		}
+		if err := e.contextErr(); err != nil {
+			return err
+		}
+		if err := e.setBytes(encoded[i]); err != nil {
+			return err
+		}
+		if err := e.create(rv.MapIndex(keys[i])); err != nil {
+			err = common.EncodeError(err, rv.MapIndex(keys[i]))
+			return common.ErrorPath(err, common.KeyPath(keys[i]))
+		}
+	}
+	return nil
+}
+
+func (e *encoder) encodeKey(k reflect.Value) ([]byte, error) {
+	w := &bytes.Buffer{}
+	sub := *e
+	sub.w, sub.buf = w, common.GetBuffer()
+	err := sub.create(k)
+	if err == nil {
+		err = sub.buf.Flush(w)
+	}
+	common.PutBuffer(sub.buf)
+	return w.Bytes(), err
+}
+
+type encodedKeys struct {
+	keys    []reflect.Value
+	encoded [][]byte
+}
+
+func (s encodedKeys) Len() int { return len(s.keys) }
+
+func (s encodedKeys) Less(i, j int) bool { return bytes.Compare(s.encoded[i], s.encoded[j]) < 0 }
+
+func (s encodedKeys) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.encoded[i], s.encoded[j] = s.encoded[j], s.encoded[i]
+}
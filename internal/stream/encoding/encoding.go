@@ -1,27 +1,59 @@
 package encoding
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"reflect"
+	"time"
 
 	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/ext"
 	"github.com/shamaton/msgpack/v3/internal/common"
 )
 
 type encoder struct {
-	w       io.Writer
-	asArray bool
-	buf     *common.Buffer
+	w              io.Writer
+	asArray        bool
+	canonical      bool
+	compactFloats  bool
+	floatAsInteger bool
+	fixedWidthInts bool
+	cycles         common.Cycles
+	formats        common.Formats
+	ctx            context.Context
+	done           <-chan struct{}
+	buf            *common.Buffer
 	common.Common
 }
 
 // Encode writes MessagePack-encoded byte array of v to writer.
 func Encode(w io.Writer, v any, asArray bool) error {
+	return EncodeWithOption(w, v, common.EncodeOption{AsArray: asArray})
+}
+
+// EncodeWithOption writes MessagePack-encoded byte array of v to writer
+// according to opt.
+func EncodeWithOption(w io.Writer, v any, opt common.EncodeOption) error {
+	return EncodeContext(context.Background(), w, v, opt)
+}
+
+// EncodeContext writes MessagePack-encoded byte array of v to writer
+// according to opt. It stops between the elements of arrays and maps
+// once ctx is done.
+func EncodeContext(ctx context.Context, w io.Writer, v any, opt common.EncodeOption) error {
 	e := encoder{
-		w:       w,
-		buf:     common.GetBuffer(),
-		asArray: asArray,
+		w:              w,
+		buf:            common.GetBuffer(),
+		asArray:        opt.AsArray,
+		canonical:      opt.Canonical,
+		compactFloats:  opt.CompactFloats,
+		floatAsInteger: opt.FloatAsInt,
+		fixedWidthInts: opt.FixedWidthInts && !opt.Canonical,
+		cycles:         opt.Cycles(),
+		formats:        common.Formats{Time: opt.TimeFormat, Duration: opt.DurationFormat},
+		ctx:            ctx,
+		done:           ctx.Done(),
 	}
 
 	rv := reflect.ValueOf(v)
@@ -35,19 +67,36 @@ func Encode(w io.Writer, v any, asArray bool) error {
 	err := e.create(rv)
 	if err == nil {
 		err = e.buf.Flush(e.w)
+	} else {
+		err = common.RootPath(common.EncodeError(err, rv), rv.Type())
 	}
 	common.PutBuffer(e.buf)
 	return err
 }
 
 func (e *encoder) create(rv reflect.Value) error {
+	if extTypeCoders != nil && rv.IsValid() && !(rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		if c, ok := extTypeCoders[rv.Type()]; ok {
+			return c.Write(ext.CreateStreamWriter(e.w, e.buf), rv)
+		}
+	}
+
 	switch rv.Kind() {
 	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint:
 		v := rv.Uint()
+		if e.fixedWidthInts {
+			return e.writeFixedUint(v, rv.Kind())
+		}
 		return e.writeUint(v)
 
 	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int:
 		v := rv.Int()
+		if e.formats.Duration != common.DurationFormatNanos && rv.Type() == common.DurationType {
+			return e.create(common.DurationValue(time.Duration(v), e.formats.Duration))
+		}
+		if e.fixedWidthInts {
+			return e.writeFixedInt(v, rv.Kind())
+		}
 		return e.writeInt(v)
 
 	case reflect.Float32:
@@ -86,10 +135,13 @@ func (e *encoder) create(rv reflect.Value) error {
 			return err
 		}
 
-		if find, err := e.writeFixedSlice(rv); err != nil {
-			return err
-		} else if find {
-			return nil
+		// fixed types are written without cancellation checks
+		if !e.fixedWidthInts && e.done == nil {
+			if find, err := e.writeFixedSlice(rv); err != nil {
+				return err
+			} else if find {
+				return nil
+			}
 		}
 
 		// func
@@ -101,12 +153,20 @@ func (e *encoder) create(rv reflect.Value) error {
 			f = e.create
 		}
 
+		if err := e.cycles.Enter(rv); err != nil {
+			return err
+		}
 		// objects
 		for i := 0; i < l; i++ {
-			if err := f(rv.Index(i)); err != nil {
+			if err := e.contextErr(); err != nil {
 				return err
 			}
+			if err := f(rv.Index(i)); err != nil {
+				err = common.EncodeError(err, rv.Index(i))
+				return common.ErrorPath(err, common.IndexPath(i))
+			}
 		}
+		e.cycles.Leave(rv)
 
 	case reflect.Array:
 		l := rv.Len()
@@ -140,9 +200,13 @@ func (e *encoder) create(rv reflect.Value) error {
 
 		// objects
 		for i := 0; i < l; i++ {
-			if err := f(rv.Index(i)); err != nil {
+			if err := e.contextErr(); err != nil {
 				return err
 			}
+			if err := f(rv.Index(i)); err != nil {
+				err = common.EncodeError(err, rv.Index(i))
+				return common.ErrorPath(err, common.IndexPath(i))
+			}
 		}
 
 	case reflect.Map:
@@ -155,22 +219,41 @@ func (e *encoder) create(rv reflect.Value) error {
 			return err
 		}
 
-		if find, err := e.writeFixedMap(rv); err != nil {
+		if err := e.cycles.Enter(rv); err != nil {
 			return err
-		} else if find {
+		}
+		if e.canonical {
+			if err := e.writeCanonicalMap(rv); err != nil {
+				return err
+			}
+			e.cycles.Leave(rv)
 			return nil
 		}
 
+		if !e.fixedWidthInts && e.done == nil {
+			if find, err := e.writeFixedMap(rv); err != nil {
+				return err
+			} else if find {
+				e.cycles.Leave(rv)
+				return nil
+			}
+		}
+
 		// key-value
 		keys := rv.MapKeys()
 		for _, k := range keys {
-			if err := e.create(k); err != nil {
+			if err := e.contextErr(); err != nil {
 				return err
 			}
+			if err := e.create(k); err != nil {
+				return common.EncodeError(err, k)
+			}
 			if err := e.create(rv.MapIndex(k)); err != nil {
-				return err
+				err = common.EncodeError(err, rv.MapIndex(k))
+				return common.ErrorPath(err, common.KeyPath(k))
 			}
 		}
+		e.cycles.Leave(rv)
 
 	case reflect.Struct:
 		return e.writeStruct(rv)
@@ -180,7 +263,13 @@ func (e *encoder) create(rv reflect.Value) error {
 			return e.writeNil()
 		}
 
-		return e.create(rv.Elem())
+		if err := e.cycles.Enter(rv); err != nil {
+			return err
+		}
+		if err := e.create(rv.Elem()); err != nil {
+			return err
+		}
+		e.cycles.Leave(rv)
 
 	case reflect.Interface:
 		return e.create(rv.Elem())
@@ -192,3 +281,8 @@ func (e *encoder) create(rv reflect.Value) error {
 	}
 	return nil
 }
+
+// contextErr returns the context error once encoding is cancelled.
+func (e *encoder) contextErr() error {
+	return common.ContextErr(e.ctx, e.done)
+}
@@ -4,12 +4,16 @@ import (
 	"reflect"
 
 	"github.com/shamaton/msgpack/v3/ext"
+	"github.com/shamaton/msgpack/v3/internal/common"
 	"github.com/shamaton/msgpack/v3/time"
 )
 
 var (
 	extCoderMap = map[reflect.Type]ext.StreamEncoder{time.StreamEncoder.Type(): time.StreamEncoder}
-	extCoders   = []ext.StreamEncoder{time.StreamEncoder}
+	// extTypeCoders holds the encoders of named types other than structs,
+	// which are looked up for every value
+	extTypeCoders map[reflect.Type]ext.StreamEncoder
+	extCoders     = []ext.StreamEncoder{time.StreamEncoder}
 )
 
 // AddExtEncoder adds encoders for extension types.
@@ -42,9 +46,28 @@ func RemoveExtEncoder(f ext.StreamEncoder) {
 
 func updateExtCoders() {
 	extCoders = make([]ext.StreamEncoder, len(extCoderMap))
+	extTypeCoders = nil
 	i := 0
 	for k := range extCoderMap {
 		extCoders[i] = extCoderMap[k]
 		i++
+		if common.IsExtType(k) {
+			if extTypeCoders == nil {
+				extTypeCoders = map[reflect.Type]ext.StreamEncoder{}
+			}
+			extTypeCoders[k] = extCoderMap[k]
+		}
 	}
 }
+
+func (e *encoder) writeExt(x common.Ext) error {
+	var buf [6]byte
+	h, err := common.ExtHeader(buf[:0], x.Type, len(x.Data))
+	if err != nil {
+		return err
+	}
+	if err = e.setBytes(h); err != nil {
+		return err
+	}
+	return e.setBytes(x.Data)
+}
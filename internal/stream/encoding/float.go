@@ -4,19 +4,45 @@ import (
 	"math"
 
 	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common/encodingutil"
 )
 
+// isFloat64AsFloat32 reports whether a float64 is written in float32 format.
+func (e *encoder) isFloat64AsFloat32(v float64) bool {
+	if e.canonical && math.IsNaN(v) {
+		return true
+	}
+	return (e.canonical || e.compactFloats) && encodingutil.IsFloat32Exact(v)
+}
+
+// floatAsInt returns the int value of a float written in int format.
+func (e *encoder) floatAsInt(v float64) (int64, bool) {
+	if !e.floatAsInteger {
+		return 0, false
+	}
+	return encodingutil.FloatAsInt(v)
+}
+
 func (e *encoder) writeFloat32(v float64) error {
+	if i, ok := e.floatAsInt(v); ok {
+		return e.writeInt(i)
+	}
 	if err := e.setByte1Int(def.Float32); err != nil {
 		return err
 	}
-	if err := e.setByte4Uint64(uint64(math.Float32bits(float32(v)))); err != nil {
+	if err := e.setByte4Uint64(uint64(encodingutil.Float32Bits(v, e.canonical))); err != nil {
 		return err
 	}
 	return nil
 }
 
 func (e *encoder) writeFloat64(v float64) error {
+	if e.isFloat64AsFloat32(v) {
+		return e.writeFloat32(v)
+	}
+	if i, ok := e.floatAsInt(v); ok {
+		return e.writeInt(i)
+	}
 	if err := e.setByte1Int(def.Float64); err != nil {
 		return err
 	}
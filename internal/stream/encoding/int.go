@@ -2,8 +2,10 @@ package encoding
 
 import (
 	"math"
+	"reflect"
 
 	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common/encodingutil"
 )
 
 func (e *encoder) isNegativeFixInt64(v int64) bool {
@@ -50,3 +52,20 @@ func (e *encoder) writeInt(v int64) error {
 	}
 	return nil
 }
+
+// writeFixedInt writes v in the int format that matches the width of k.
+func (e *encoder) writeFixedInt(v int64, k reflect.Kind) error {
+	code, size := encodingutil.IntFormat(k)
+	if err := e.setByte1Int(int(code)); err != nil {
+		return err
+	}
+	switch size {
+	case def.Byte1:
+		return e.setByte1Int64(v)
+	case def.Byte2:
+		return e.setByte2Int64(v)
+	case def.Byte4:
+		return e.setByte4Int64(v)
+	}
+	return e.setByte8Int64(v)
+}
@@ -4,10 +4,12 @@ import (
 	"math"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/shamaton/msgpack/v3/def"
 	"github.com/shamaton/msgpack/v3/ext"
 	"github.com/shamaton/msgpack/v3/internal/common"
+	"github.com/shamaton/msgpack/v3/internal/common/encodingutil"
 )
 
 type structCache struct {
@@ -16,6 +18,9 @@ type structCache struct {
 	omits  []bool
 	noOmit bool
 
+	// canonicalOrder is the field order of map format in canonical mode
+	canonicalOrder []int
+
 	// fast path detection
 	hasEmbedded bool
 
@@ -26,6 +31,9 @@ type structCache struct {
 	indexes   [][]int   // field path (support for embedded structs)
 	omitPaths [][][]int // embedded omitempty parent paths
 
+	// formats holds the formats set by field tags
+	formats map[int]common.FormatTags
+
 	common.Common
 }
 
@@ -61,6 +69,9 @@ func shouldOmitByParent(rv reflect.Value, omitPaths [][]int) bool {
 }
 
 func (e *encoder) getStructWriter(typ reflect.Type) structWriteFunc {
+	if typ == common.ExtType || typ == common.TimeType && e.formats.Time != common.TimeFormatExt {
+		return e.writeStruct
+	}
 	for i := range extCoders {
 		if extCoders[i].Type() == typ {
 			return func(rv reflect.Value) error {
@@ -77,6 +88,13 @@ func (e *encoder) getStructWriter(typ reflect.Type) structWriteFunc {
 }
 
 func (e *encoder) writeStruct(rv reflect.Value) error {
+	if rv.Type() == common.ExtType {
+		return e.writeExt(rv.Interface().(common.Ext))
+	}
+	if rv.Type() == common.TimeType && e.formats.Time != common.TimeFormatExt {
+		return e.create(common.TimeValue(rv.Interface().(time.Time), e.formats.Time))
+	}
+
 	for i := range extCoders {
 		if extCoders[i].Type() == rv.Type() {
 			w := ext.CreateStreamWriter(e.w, e.buf)
@@ -127,14 +145,22 @@ func (e *encoder) writeStructArray(rv reflect.Value) error {
 			if shouldOmitByParent(rv, c.omitPaths[i]) || !ok {
 				fieldValue = reflect.Value{}
 			}
-			if err := e.create(fieldValue); err != nil {
-				return err
+			f := e.useFormats(c, i)
+			err := e.create(fieldValue)
+			e.formats = f
+			if err != nil {
+				err = common.EncodeError(err, fieldValue)
+				return common.ErrorPath(err, common.FieldPath(rv.Type(), c.indexes[i]))
 			}
 		}
 	} else {
 		for i := 0; i < num; i++ {
-			if err := e.create(rv.Field(c.simpleIndexes[i])); err != nil {
-				return err
+			f := e.useFormats(c, i)
+			err := e.create(rv.Field(c.simpleIndexes[i]))
+			e.formats = f
+			if err != nil {
+				err = common.EncodeError(err, rv.Field(c.simpleIndexes[i]))
+				return common.ErrorPath(err, common.FieldPath(rv.Type(), c.simpleIndexes[i:i+1]))
 			}
 		}
 	}
@@ -188,7 +214,8 @@ func (e *encoder) writeStructMap(rv reflect.Value) error {
 
 	if c.hasEmbedded {
 		num := len(c.indexes)
-		for i := 0; i < num; i++ {
+		for j := 0; j < num; j++ {
+			i := e.fieldIndex(c, j)
 			fieldValue, ok := getFieldByPath(rv, c.indexes[i])
 			if shouldOmitByParent(rv, c.omitPaths[i]) || !ok {
 				continue
@@ -197,21 +224,30 @@ func (e *encoder) writeStructMap(rv reflect.Value) error {
 				if err := e.writeString(c.names[i]); err != nil {
 					return err
 				}
-				if err := e.create(fieldValue); err != nil {
-					return err
+				f := e.useFormats(c, i)
+				err := e.create(fieldValue)
+				e.formats = f
+				if err != nil {
+					err = common.EncodeError(err, fieldValue)
+					return common.ErrorPath(err, common.FieldPath(rv.Type(), c.indexes[i]))
 				}
 			}
 		}
 	} else {
 		num := len(c.simpleIndexes)
-		for i := 0; i < num; i++ {
+		for j := 0; j < num; j++ {
+			i := e.fieldIndex(c, j)
 			fieldValue := rv.Field(c.simpleIndexes[i])
 			if c.noOmit || !c.omits[i] || !fieldValue.IsZero() {
 				if err := e.writeString(c.names[i]); err != nil {
 					return err
 				}
-				if err := e.create(fieldValue); err != nil {
-					return err
+				f := e.useFormats(c, i)
+				err := e.create(fieldValue)
+				e.formats = f
+				if err != nil {
+					err = common.EncodeError(err, fieldValue)
+					return common.ErrorPath(err, common.FieldPath(rv.Type(), c.simpleIndexes[i:i+1]))
 				}
 			}
 		}
@@ -219,6 +255,24 @@ func (e *encoder) writeStructMap(rv reflect.Value) error {
 	return nil
 }
 
+// useFormats applies the format tags of the i-th field of c and
+// returns the formats to restore afterwards.
+func (e *encoder) useFormats(c *structCache, i int) common.Formats {
+	f := e.formats
+	if t, ok := c.formats[i]; ok {
+		e.formats = t.Apply(f)
+	}
+	return f
+}
+
+// fieldIndex returns the cache index of the j-th field written in map format.
+func (e *encoder) fieldIndex(c *structCache, j int) int {
+	if e.canonical {
+		return c.canonicalOrder[j]
+	}
+	return j
+}
+
 func (e *encoder) getStructCache(rv reflect.Value) *structCache {
 	t := rv.Type()
 	cache, find := cachemap.Load(t)
@@ -254,6 +308,8 @@ func (e *encoder) getStructCache(rv reflect.Value) *structCache {
 		}
 	}
 	c.noOmit = omitCount == 0
+	c.formats = common.FieldFormats(fields)
+	c.canonicalOrder = encodingutil.CanonicalStringOrder(c.names)
 	cachemap.Store(t, c)
 	return c
 }
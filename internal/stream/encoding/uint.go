@@ -2,8 +2,10 @@ package encoding
 
 import (
 	"math"
+	"reflect"
 
 	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common/encodingutil"
 )
 
 func (e *encoder) writeUint(v uint64) error {
@@ -42,3 +44,20 @@ func (e *encoder) writeUint(v uint64) error {
 	}
 	return nil
 }
+
+// writeFixedUint writes v in the uint format that matches the width of k.
+func (e *encoder) writeFixedUint(v uint64, k reflect.Kind) error {
+	code, size := encodingutil.UintFormat(k)
+	if err := e.setByte1Int(int(code)); err != nil {
+		return err
+	}
+	switch size {
+	case def.Byte1:
+		return e.setByte1Uint64(v)
+	case def.Byte2:
+		return e.setByte2Uint64(v)
+	case def.Byte4:
+		return e.setByte4Uint64(v)
+	}
+	return e.setByte8Uint64(v)
+}
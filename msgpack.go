@@ -6,6 +6,7 @@ import (
 
 	"github.com/shamaton/msgpack/v3/def"
 	"github.com/shamaton/msgpack/v3/ext"
+	"github.com/shamaton/msgpack/v3/internal/common"
 	"github.com/shamaton/msgpack/v3/internal/decoding"
 	"github.com/shamaton/msgpack/v3/internal/encoding"
 	streamdecoding "github.com/shamaton/msgpack/v3/internal/stream/decoding"
@@ -39,43 +40,103 @@ func UnmarshalRead(r io.Reader, v interface{}) error {
 	return streamdecoding.Decode(r, v, StructAsArray)
 }
 
+// IsCanonical checks that data holds exactly one value encoded the way
+// EncodeOptions.Canonical does. The error wraps def.ErrNotCanonical and
+// reports the byte offset of the first non-canonical value.
+func IsCanonical(data []byte) error {
+	return decoding.IsCanonical(data)
+}
+
+// Ext is an extension value of a type that has no registered decoder.
+// Decoding such an extension into an interface gives an Ext, and
+// encoding an Ext writes its type and data as they are, so that unknown
+// extensions can be passed through. A value of type Ext is always decoded
+// this way, whether its type has a decoder or not.
+type Ext = common.Ext
+
 // AddExtCoder adds encoders for extension types.
 func AddExtCoder(e ext.Encoder, d ext.Decoder) error {
+	return AddExtCoderV2(e, ext.AdaptDecoder(d))
+}
+
+// AddExtCoderV2 adds encoders for extension types, whose decoder decodes
+// into the destination value.
+func AddExtCoderV2(e ext.Encoder, d ext.DecoderV2) error {
 	if e.Code() != d.Code() {
 		return fmt.Errorf("code different %d:%d", e.Code(), d.Code())
 	}
+	if err := checkZoneCode(e.Code()); err != nil {
+		return err
+	}
 	encoding.AddExtEncoder(e)
-	decoding.AddExtDecoder(d)
+	decoding.AddExtDecoderV2(d)
+	if common.IsExtType(e.Type()) {
+		decoding.AddExtTypeDecoder(e.Type(), d)
+	}
+	return nil
+}
+
+// checkZoneCode returns an error if zoned time.Time values use code.
+func checkZoneCode(code int8) error {
+	if zc, ok := time.ZoneCode(); ok && zc == code {
+		return fmt.Errorf("code %d is used by zoned time.Time values, see time.SetZoneCode", code)
+	}
 	return nil
 }
 
 // AddExtStreamCoder adds stream encoders for extension types.
 func AddExtStreamCoder(e ext.StreamEncoder, d ext.StreamDecoder) error {
+	return AddExtStreamCoderV2(e, ext.AdaptStreamDecoder(d))
+}
+
+// AddExtStreamCoderV2 adds stream encoders for extension types, whose
+// decoder decodes into the destination value.
+func AddExtStreamCoderV2(e ext.StreamEncoder, d ext.StreamDecoderV2) error {
 	if e.Code() != d.Code() {
 		return fmt.Errorf("code different %d:%d", e.Code(), d.Code())
 	}
+	if err := checkZoneCode(e.Code()); err != nil {
+		return err
+	}
 	streamencoding.AddExtEncoder(e)
-	streamdecoding.AddExtDecoder(d)
+	streamdecoding.AddExtDecoderV2(d)
+	if common.IsExtType(e.Type()) {
+		streamdecoding.AddExtTypeDecoder(e.Type(), d)
+	}
 	return nil
 }
 
 // RemoveExtCoder removes encoders for extension types.
 func RemoveExtCoder(e ext.Encoder, d ext.Decoder) error {
+	return RemoveExtCoderV2(e, ext.AdaptDecoder(d))
+}
+
+// RemoveExtCoderV2 removes encoders for extension types that
+// AddExtCoderV2 added.
+func RemoveExtCoderV2(e ext.Encoder, d ext.DecoderV2) error {
 	if e.Code() != d.Code() {
 		return fmt.Errorf("code different %d:%d", e.Code(), d.Code())
 	}
 	encoding.RemoveExtEncoder(e)
-	decoding.RemoveExtDecoder(d)
+	decoding.RemoveExtDecoderV2(d)
+	decoding.RemoveExtTypeDecoder(e.Type())
 	return nil
 }
 
 // RemoveExtStreamCoder removes stream encoders for extension types.
 func RemoveExtStreamCoder(e ext.StreamEncoder, d ext.StreamDecoder) error {
+	return RemoveExtStreamCoderV2(e, ext.AdaptStreamDecoder(d))
+}
+
+// RemoveExtStreamCoderV2 removes stream encoders for extension types
+// that AddExtStreamCoderV2 added.
+func RemoveExtStreamCoderV2(e ext.StreamEncoder, d ext.StreamDecoderV2) error {
 	if e.Code() != d.Code() {
 		return fmt.Errorf("code different %d:%d", e.Code(), d.Code())
 	}
 	streamencoding.RemoveExtEncoder(e)
-	streamdecoding.RemoveExtDecoder(d)
+	streamdecoding.RemoveExtDecoderV2(d)
+	streamdecoding.RemoveExtTypeDecoder(e.Type())
 	return nil
 }
 
@@ -93,3 +154,36 @@ func SetDecodedTimeAsUTC() {
 func SetDecodedTimeAsLocal() {
 	time.SetDecodedAsLocal(true)
 }
+
+// SetEncodedTimeWithZone sets time.Time values to be encoded with their
+// location, so that receivers that call SetDecodedTimeWithZone decode them
+// with the same location. See time.SetEncodedWithZone for the format.
+func SetEncodedTimeWithZone() {
+	time.SetEncodedWithZone(true)
+}
+
+// SetEncodedTimeAsTimestamp sets time.Time values to be encoded as
+// standard timestamps, which is the default.
+func SetEncodedTimeAsTimestamp() {
+	time.SetEncodedWithZone(false)
+}
+
+// SetDecodedTimeWithZone sets time.Time values encoded with their location
+// to be decoded with it. Standard timestamps still decode.
+func SetDecodedTimeWithZone() {
+	time.SetDecodedWithZone(true)
+}
+
+// SetDecodedTimeWithoutZone sets time.Time values encoded with their
+// location to be left undecoded, which is the default.
+func SetDecodedTimeWithoutZone() {
+	time.SetDecodedWithZone(false)
+}
+
+// SetEncodedTimestampWidth sets the timestamp width that time.Time values
+// are encoded with, such as time.Width64 for receivers that only read
+// timestamp64. Times that the width can't represent fail to encode.
+// time.WidthAuto selects the smallest width, which is the default.
+func SetEncodedTimestampWidth(w time.Width) {
+	time.SetEncodedWidth(w)
+}
@@ -2936,6 +2936,100 @@ func TestExt(t *testing.T) {
 	})
 }
 
+func TestTimeZoneExt(t *testing.T) {
+	msgpack.SetEncodedTimeWithZone()
+	defer msgpack.SetEncodedTimeAsTimestamp()
+	msgpack.SetDecodedTimeWithZone()
+	defer msgpack.SetDecodedTimeWithoutZone()
+
+	loc, err := time.LoadLocation("Europe/Paris")
+	NoError(t, err)
+	type st struct {
+		At  time.Time
+		Any any
+	}
+	in := st{At: time.Date(2024, 3, 31, 1, 30, 0, 0, loc), Any: time.Date(2024, 3, 31, 3, 30, 0, 0, loc)}
+
+	b, err := msgpack.MarshalAsMap(in)
+	NoError(t, err)
+	var out1 st
+	NoError(t, msgpack.UnmarshalAsMap(b, &out1))
+
+	buf := bytes.Buffer{}
+	NoError(t, msgpack.MarshalWriteAsMap(&buf, in))
+	tu.EqualSlice(t, buf.Bytes(), b)
+	var out2 st
+	NoError(t, msgpack.UnmarshalReadAsMap(&buf, &out2))
+
+	for _, out := range []st{out1, out2} {
+		tu.Equal(t, out.At.Equal(in.At), true)
+		tu.Equal(t, out.At.Location(), loc)
+		at, ok := out.Any.(time.Time)
+		tu.Equal(t, ok, true)
+		tu.Equal(t, at.Equal(in.Any.(time.Time)), true)
+		tu.Equal(t, at.Location(), loc)
+	}
+
+	t.Run("StandardTimestamp", func(t *testing.T) {
+		// 1000 seconds as fixext4
+		data := []byte{def.Fixext4, 0xff, 0, 0, 0x03, 0xe8}
+		var v1, v2 time.Time
+		NoError(t, msgpack.Unmarshal(data, &v1))
+		NoError(t, msgpack.UnmarshalRead(bytes.NewReader(data), &v2))
+		tu.Equal(t, v1.Unix(), int64(1000))
+		tu.Equal(t, v2.Unix(), int64(1000))
+	})
+
+	t.Run("DecodeOnly", func(t *testing.T) {
+		msgpack.SetEncodedTimeAsTimestamp()
+		defer msgpack.SetEncodedTimeWithZone()
+		var v1, v2 st
+		NoError(t, msgpack.UnmarshalAsMap(b, &v1))
+		NoError(t, msgpack.UnmarshalReadAsMap(bytes.NewReader(b), &v2))
+		tu.Equal(t, v1.At.Location(), loc)
+		tu.Equal(t, v2.At.Location(), loc)
+	})
+
+	t.Run("CodeCollision", func(t *testing.T) {
+		c := namedCoders[extColor, uint32](0x7f)
+		tu.Error(t, msgpack.AddExtCoder(c.e, c.d))
+		tu.Error(t, msgpack.AddExtStreamCoder(c.se, c.sd))
+		err := msgpack.RegisterExt(0x7f, marshalPoint, unmarshalPoint)
+		tu.Error(t, err)
+
+		msgpack.SetEncodedTimeAsTimestamp()
+		msgpack.SetDecodedTimeWithoutZone()
+		defer msgpack.SetDecodedTimeWithZone()
+		defer msgpack.SetEncodedTimeWithZone()
+		NoError(t, msgpack.AddExtCoder(c.e, c.d))
+		NoError(t, msgpack.RemoveExtCoder(c.e, c.d))
+	})
+}
+
+func TestTimestampWidth(t *testing.T) {
+	msgpack.SetEncodedTimestampWidth(extTime.Width64)
+	defer msgpack.SetEncodedTimestampWidth(extTime.WidthAuto)
+
+	in := []time.Time{time.Unix(1000, 0).UTC()}
+	b, err := msgpack.Marshal(in)
+	NoError(t, err)
+	tu.Equal(t, b[1], def.Fixext8)
+
+	buf := bytes.Buffer{}
+	NoError(t, msgpack.MarshalWrite(&buf, in))
+	tu.EqualSlice(t, buf.Bytes(), b)
+
+	var out []time.Time
+	NoError(t, msgpack.Unmarshal(b, &out))
+	tu.Equal(t, out[0].Equal(in[0]), true)
+
+	in = []time.Time{time.Unix(-1, 0)}
+	_, err = msgpack.Marshal(in)
+	tu.IsError(t, err, def.ErrValueOutOfRange)
+	err = msgpack.MarshalWrite(&buf, in)
+	tu.IsError(t, err, def.ErrValueOutOfRange)
+}
+
 type ExtStruct struct {
 	Int8        int8
 	Int16       int16
@@ -3262,6 +3356,287 @@ func (s *testExt2StreamEncoder) Write(_ ext.StreamWriter, _ reflect.Value) error
 	return fmt.Errorf("should not reach this line!! code %x", s.Code())
 }
 
+type (
+	extUserID [4]byte
+	extColor  uint32
+	extName   string
+	extTags   []string
+	extAttrs  map[string]int
+	extRef    *extColor
+)
+
+func TestExtNamedTypes(t *testing.T) {
+	coders := []struct {
+		e  ext.Encoder
+		d  ext.Decoder
+		se ext.StreamEncoder
+		sd ext.StreamDecoder
+	}{
+		namedCoders[extUserID, [4]byte](20),
+		namedCoders[extColor, uint32](21),
+		namedCoders[extName, string](22),
+		namedCoders[extTags, []string](23),
+		namedCoders[extAttrs, map[string]int](24),
+		namedCoders[extRef, *extColor](25),
+		namedCoders[int, int](26),
+		namedCoders[[]byte, []byte](27),
+	}
+	for _, c := range coders {
+		NoError(t, msgpack.AddExtCoder(c.e, c.d))
+		NoError(t, msgpack.AddExtStreamCoder(c.se, c.sd))
+	}
+	defer func() {
+		for _, c := range coders {
+			NoError(t, msgpack.RemoveExtCoder(c.e, c.d))
+			NoError(t, msgpack.RemoveExtStreamCoder(c.se, c.sd))
+		}
+	}()
+
+	// every value is an ext8 of its code
+	isExt := func(code byte) checker {
+		return func(d []byte) bool {
+			return len(d) > 2 && d[0] == def.Ext8 && d[2] == code
+		}
+	}
+	encdec(t,
+		encdecArg[extUserID]{n: "Array", v: extUserID{1, 2, 3, 4}, c: isExt(20)},
+		encdecArg[extUserID]{n: "ArrayZero", v: extUserID{}, c: isExt(20)},
+	)
+	encdec(t, encdecArg[extColor]{n: "Int", v: extColor(0xff8000), c: isExt(21)})
+	encdec(t, encdecArg[extName]{n: "String", v: extName("name"), c: isExt(22)})
+	encdec(t, encdecArg[extTags]{n: "Slice", v: extTags{"a", "b"}, c: isExt(23)})
+	encdec(t, encdecArg[extAttrs]{n: "Map", v: extAttrs{"a": 1}, c: isExt(24)})
+
+	type st struct {
+		ID     extUserID
+		Color  extColor
+		Name   extName
+		Tags   extTags
+		Attrs  extAttrs
+		Colors []extColor
+		Names  map[string]extName
+		IDs    [2]extUserID
+		Ptr    *extColor
+		Nil    *extColor
+		Ref    extRef
+	}
+	color := extColor(7)
+	v := st{
+		ID:     extUserID{9, 8, 7, 6},
+		Color:  3,
+		Name:   "x",
+		Tags:   extTags{"t"},
+		Attrs:  extAttrs{"k": 2},
+		Colors: []extColor{1, 2},
+		Names:  map[string]extName{"a": "b"},
+		IDs:    [2]extUserID{{1}, {2}},
+		Ptr:    &color,
+	}
+	encdec(t, encdecArg[st]{n: "Fields", v: v})
+
+	t.Run("NilPointer", func(t *testing.T) {
+		// nil pointers are encoded as nil instead of passed to the encoder
+		type ptrs struct {
+			Nil *extColor
+			Ref extRef
+		}
+		d, err := msgpack.Marshal(ptrs{})
+		NoError(t, err)
+		want, err := msgpack.Marshal(struct{ Nil, Ref *int }{})
+		NoError(t, err)
+		tu.EqualSlice(t, d, want)
+		buf := &bytes.Buffer{}
+		NoError(t, msgpack.MarshalWrite(buf, ptrs{}))
+		tu.EqualSlice(t, buf.Bytes(), d)
+	})
+
+	t.Run("Unnamed", func(t *testing.T) {
+		// coders of predeclared and unnamed types are not used for every value
+		for _, v := range []any{5, []byte{1, 2}} {
+			d, err := msgpack.Marshal(v)
+			NoError(t, err)
+			buf := &bytes.Buffer{}
+			NoError(t, msgpack.MarshalWrite(buf, v))
+			tu.EqualSlice(t, buf.Bytes(), d)
+			tu.Equal(t, d[0] != def.Ext8, true)
+		}
+	})
+
+	t.Run("PlainData", func(t *testing.T) {
+		// data that is not an extension decodes as usual
+		d, err := msgpack.Marshal(uint32(5))
+		NoError(t, err)
+		var c1, c2 extColor
+		NoError(t, msgpack.Unmarshal(d, &c1))
+		NoError(t, msgpack.UnmarshalRead(bytes.NewReader(d), &c2))
+		tu.Equal(t, c1, extColor(5))
+		tu.Equal(t, c2, extColor(5))
+	})
+
+	t.Run("OtherExt", func(t *testing.T) {
+		d, err := msgpack.Marshal(extName("x"))
+		NoError(t, err)
+		var c extColor
+		tu.IsError(t, msgpack.Unmarshal(d, &c), msgpack.Error)
+		tu.IsError(t, msgpack.UnmarshalRead(bytes.NewReader(d), &c), msgpack.Error)
+	})
+}
+
+func TestExtPassthrough(t *testing.T) {
+	// extension types without a decoder
+	raw := [][]byte{
+		{def.Fixext1, 0x40, 0x01},
+		{def.Fixext16, 0x41, 0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		{def.Ext8, 0x00, 0x42},
+		{def.Ext8, 0x03, 0xc0, 'a', 'b', 'c'},
+		append([]byte{def.Ext16, 0x01, 0x00, 0x43}, make([]byte, 256)...),
+	}
+	for _, b := range raw {
+		t.Run(fmt.Sprintf("Raw%x", b[:2]), func(t *testing.T) {
+			var v1, v2 any
+			NoError(t, msgpack.Unmarshal(b, &v1))
+			NoError(t, msgpack.UnmarshalRead(bytes.NewReader(b), &v2))
+			tu.Equal(t, v1, v2)
+			x, ok := v1.(msgpack.Ext)
+			tu.Equal(t, ok, true)
+			tu.Equal(t, byte(x.Type), b[len(b)-len(x.Data)-1])
+
+			d1, err := msgpack.Marshal(v1)
+			NoError(t, err)
+			buf := &bytes.Buffer{}
+			NoError(t, msgpack.MarshalWrite(buf, v2))
+			tu.EqualSlice(t, d1, b)
+			tu.EqualSlice(t, buf.Bytes(), b)
+		})
+	}
+
+	type st struct {
+		A msgpack.Ext
+		B *msgpack.Ext
+		C []any
+		D map[string]msgpack.Ext
+	}
+	v := st{
+		A: msgpack.Ext{Type: 5, Data: []byte{1, 2, 3}},
+		B: &msgpack.Ext{Type: -5, Data: []byte{}},
+		C: []any{1, msgpack.Ext{Type: 6, Data: []byte{1, 2, 3, 4}}},
+		D: map[string]msgpack.Ext{"a": {Type: 127, Data: make([]byte, 300)}},
+	}
+	encdec(t, encdecArg[st]{
+		n:      "Fields",
+		v:      v,
+		skipEq: true,
+		vc: func(r st) error {
+			if !reflect.DeepEqual(r.A, v.A) || !reflect.DeepEqual(r.B, v.B) ||
+				!reflect.DeepEqual(r.C[1], v.C[1]) || !reflect.DeepEqual(r.D, v.D) {
+				return fmt.Errorf("different value %v", r)
+			}
+			return nil
+		},
+	})
+
+	t.Run("Registered", func(t *testing.T) {
+		// an Ext receives extension types that have a decoder as they are
+		tm := time.Unix(1, 0)
+		b, err := msgpack.Marshal(tm)
+		NoError(t, err)
+		var x1, x2 msgpack.Ext
+		NoError(t, msgpack.Unmarshal(b, &x1))
+		NoError(t, msgpack.UnmarshalRead(bytes.NewReader(b), &x2))
+		tu.Equal(t, x1.Type, int8(-1))
+		tu.EqualSlice(t, x1.Data, b[2:])
+		tu.Equal(t, x2.Type, int8(-1))
+		tu.EqualSlice(t, x2.Data, b[2:])
+
+		var a any
+		NoError(t, msgpack.Unmarshal(b, &a))
+		tu.Equal(t, a.(time.Time).Equal(tm), true)
+	})
+}
+
+// namedCoders returns coders that write a value of T as an ext8 of code
+// that holds its value of the underlying type U.
+func namedCoders[T, U any](code int8) (c struct {
+	e  ext.Encoder
+	d  ext.Decoder
+	se ext.StreamEncoder
+	sd ext.StreamDecoder
+},
+) {
+	n := &namedCodec[T, U]{code: code}
+	c.e, c.d = &namedEncoder[T, U]{n}, &namedDecoder[T, U]{n}
+	c.se, c.sd = &namedStreamEncoder[T, U]{n}, &namedStreamDecoder[T, U]{n}
+	return c
+}
+
+type namedCodec[T, U any] struct {
+	code int8
+}
+
+func (n *namedCodec[T, U]) Code() int8 {
+	return n.code
+}
+
+func (n *namedCodec[T, U]) Type() reflect.Type {
+	return reflect.TypeFor[T]()
+}
+
+func (n *namedCodec[T, U]) encode(value reflect.Value) []byte {
+	data, err := msgpack.Marshal(value.Convert(reflect.TypeFor[U]()).Interface())
+	if err != nil {
+		panic(err)
+	}
+	return append([]byte{def.Ext8, byte(len(data)), byte(n.code)}, data...)
+}
+
+func (n *namedCodec[T, U]) decode(data []byte) (any, error) {
+	var u U
+	if err := msgpack.Unmarshal(data, &u); err != nil {
+		return nil, err
+	}
+	return reflect.ValueOf(u).Convert(reflect.TypeFor[T]()).Interface(), nil
+}
+
+type namedEncoder[T, U any] struct{ *namedCodec[T, U] }
+
+func (e *namedEncoder[T, U]) CalcByteSize(value reflect.Value) (int, error) {
+	return len(e.encode(value)), nil
+}
+
+func (e *namedEncoder[T, U]) WriteToBytes(value reflect.Value, offset int, bytes *[]byte) int {
+	return offset + copy((*bytes)[offset:], e.encode(value))
+}
+
+type namedDecoder[T, U any] struct{ *namedCodec[T, U] }
+
+func (d *namedDecoder[T, U]) IsType(offset int, data *[]byte) bool {
+	b := *data
+	return len(b) > offset+2 && b[offset] == def.Ext8 && int8(b[offset+2]) == d.code
+}
+
+func (d *namedDecoder[T, U]) AsValue(offset int, _ reflect.Kind, data *[]byte) (any, int, error) {
+	b := *data
+	end := offset + 3 + int(b[offset+1])
+	v, err := d.decode(b[offset+3 : end])
+	return v, end, err
+}
+
+type namedStreamEncoder[T, U any] struct{ *namedCodec[T, U] }
+
+func (e *namedStreamEncoder[T, U]) Write(w ext.StreamWriter, value reflect.Value) error {
+	return w.WriteBytes(e.encode(value))
+}
+
+type namedStreamDecoder[T, U any] struct{ *namedCodec[T, U] }
+
+func (d *namedStreamDecoder[T, U]) IsType(code byte, innerType int8, _ int) bool {
+	return code == def.Ext8 && innerType == d.code
+}
+
+func (d *namedStreamDecoder[T, U]) ToValue(_ byte, data []byte, _ reflect.Kind) (any, error) {
+	return d.decode(data)
+}
+
 /////////////////////////////////////////////////////////
 
 type Issue44Struct1 struct {
@@ -0,0 +1,270 @@
+package msgpack
+
+import (
+	"io"
+
+	"github.com/shamaton/msgpack/v3/internal/common"
+	"github.com/shamaton/msgpack/v3/internal/decoding"
+	"github.com/shamaton/msgpack/v3/internal/encoding"
+	streamdecoding "github.com/shamaton/msgpack/v3/internal/stream/decoding"
+	streamencoding "github.com/shamaton/msgpack/v3/internal/stream/encoding"
+)
+
+// EncodeOptions configures encoding.
+// The zero value encodes the same as MarshalAsMap.
+type EncodeOptions struct {
+	// StructAsArray encodes structs as array format instead of map format.
+	StructAsArray bool
+
+	// Canonical encodes every value in a single byte form: the shortest
+	// int, length and float formats, map keys and struct fields sorted by
+	// their encoded bytes, and every NaN as float32 0x7fc00000.
+	// Maps whose keys encode to the same bytes are rejected.
+	Canonical bool
+
+	// CompactFloats writes a float64 in float32 format when the value
+	// converts to float32 and back without loss, such as 0.5 or 1.25.
+	CompactFloats bool
+
+	// FloatAsInt writes whole-number floats, such as 3.0, in int format.
+	// Negative zero and values outside the int64 range keep float format.
+	FloatAsInt bool
+
+	// FixedWidthInts writes every int and uint in the format that matches
+	// its Go kind, so an int64 holding 5 is written as int 64 instead of
+	// positive fixint. int and uint follow the platform word size.
+	// It is ignored when Canonical is set.
+	FixedWidthInts bool
+
+	// CycleDepth is how many pointers, maps and slices may be nested
+	// before the encoder starts tracking them to detect cycles, which fail
+	// with def.ErrCycle. Zero uses DefaultCycleDepth and a negative value
+	// tracks them from the start.
+	CycleDepth int
+
+	// TimeFormat writes time.Time values as Unix seconds, milliseconds,
+	// float seconds or RFC 3339 strings instead of the timestamp
+	// extension. A field tag such as `msgpack:"ts,time=unixms"` sets the
+	// format of that field and the values within it, using the names
+	// ext, unix, unixms, unixfloat and rfc3339.
+	TimeFormat TimeFormat
+
+	// DurationFormat writes time.Duration values as seconds, milliseconds,
+	// float seconds or strings such as "1h30m" instead of nanoseconds.
+	// Seconds and milliseconds are truncated. A field tag such as
+	// `msgpack:"timeout,duration=ms"` sets the format of that field, using
+	// the names ns, s, ms, float and string.
+	DurationFormat DurationFormat
+}
+
+// DefaultCycleDepth is the nesting after which cycles are detected when
+// EncodeOptions.CycleDepth is zero, and by Marshal and MarshalWrite.
+const DefaultCycleDepth = common.DefaultCycleDepth
+
+// Marshal returns the MessagePack-encoded byte array of v.
+func (o EncodeOptions) Marshal(v interface{}) ([]byte, error) {
+	return encoding.EncodeWithOption(v, o.option())
+}
+
+// MarshalWrite writes MessagePack-encoded byte array of v to writer.
+func (o EncodeOptions) MarshalWrite(w io.Writer, v interface{}) error {
+	return streamencoding.EncodeWithOption(w, v, o.option())
+}
+
+func (o EncodeOptions) option() common.EncodeOption {
+	return common.EncodeOption{
+		AsArray:        o.StructAsArray,
+		Canonical:      o.Canonical,
+		CompactFloats:  o.CompactFloats,
+		FloatAsInt:     o.FloatAsInt,
+		FixedWidthInts: o.FixedWidthInts,
+		CycleDepth:     o.CycleDepth,
+		TimeFormat:     o.TimeFormat,
+		DurationFormat: o.DurationFormat,
+	}
+}
+
+// DecodeOptions configures decoding.
+// The zero value decodes the same as UnmarshalAsMap.
+type DecodeOptions struct {
+	// StructAsArray decodes structs from array format instead of map format.
+	StructAsArray bool
+
+	// ZeroCopy makes decoded strings share memory with the input data
+	// instead of being copied. Decoded []byte values always share it,
+	// whether ZeroCopy is set or not.
+	// The input must not be modified while any decoded value is in use.
+	// It only applies to Unmarshal; values read from a stream are always
+	// copied.
+	ZeroCopy bool
+
+	// Interner, if set, shares one allocation between equal decoded
+	// strings such as repeated map keys. Share an Interner between
+	// decodes to reuse strings across calls.
+	Interner *Interner
+
+	// MaxDepth limits how deeply arrays, maps and structs may be nested.
+	// Exceeding it returns def.ErrMaxDepthExceeded. Zero uses
+	// DefaultMaxDepth.
+	MaxDepth int
+
+	// MaxStringLen, MaxBinLen, MaxArrayLen and MaxMapLen limit the length
+	// of a single string, bin, array and map. They are checked before
+	// allocating and fail with def.ErrMaxStringLenExceeded,
+	// def.ErrMaxBinLenExceeded, def.ErrMaxArrayLenExceeded and
+	// def.ErrMaxMapLenExceeded. Zero means no limit.
+	MaxStringLen int
+	MaxBinLen    int
+	MaxArrayLen  int
+	MaxMapLen    int
+
+	// MaxTotalBytes limits the size of the input. A stream is cut off
+	// before reading past it with def.ErrMaxTotalBytesExceeded.
+	// Zero means no limit.
+	MaxTotalBytes int
+
+	// ContinueOnError skips a struct field, element or map value that
+	// fails to decode and goes on with the rest. The failures are returned
+	// together as DecodeErrors once decoding ends. A value that fails
+	// inside an interface{} or a slice or map of basic types is skipped as
+	// a whole. It only applies to Unmarshal; reading from a stream stops
+	// at the first error.
+	ContinueOnError bool
+
+	// WeakTypes converts values whose format does not match the Go type:
+	// numeric strings to ints, uints and floats, numbers to strings, 0 and
+	// 1 to bool, and a single value to a one-element slice. Strings holding
+	// whole-number floats such as "3.0" convert to ints, with the same
+	// range checks as float values. Other values decode as usual.
+	WeakTypes bool
+
+	// IntOverflow selects what happens when an integer does not fit the
+	// Go integer type it is decoded into. The default fails with
+	// def.ErrValueOutOfRange.
+	IntOverflow IntOverflow
+
+	// FloatToInt selects what happens when a float is decoded into a Go
+	// integer type. The default drops the fractional part.
+	FloatToInt FloatToInt
+
+	// TimeFormat decodes time.Time values from integers, floats of
+	// seconds and RFC 3339 strings besides the timestamp extension when it
+	// is not TimeFormatExt. Integers are milliseconds for
+	// TimeFormatUnixMilli and seconds otherwise. A field tag such as
+	// `msgpack:"ts,time=unixms"` sets it for that field.
+	TimeFormat TimeFormat
+
+	// DurationFormat decodes time.Duration values from integers, floats of
+	// seconds and strings such as "1h30m" when it is not
+	// DurationFormatNanos. Integers are milliseconds for DurationFormatMilli
+	// and seconds otherwise. A field tag such as
+	// `msgpack:"timeout,duration=ms"` sets it for that field.
+	DurationFormat DurationFormat
+}
+
+// TimeFormat selects how time.Time values are represented.
+type TimeFormat = common.TimeFormat
+
+const (
+	// TimeFormatExt is the timestamp extension type, which is the default.
+	TimeFormatExt = common.TimeFormatExt
+	// TimeFormatUnix is an integer of seconds since the Unix epoch.
+	TimeFormatUnix = common.TimeFormatUnix
+	// TimeFormatUnixMilli is an integer of milliseconds since the Unix epoch.
+	TimeFormatUnixMilli = common.TimeFormatUnixMilli
+	// TimeFormatUnixFloat is a float of seconds since the Unix epoch.
+	TimeFormatUnixFloat = common.TimeFormatUnixFloat
+	// TimeFormatRFC3339 is a string formatted by time.RFC3339Nano.
+	TimeFormatRFC3339 = common.TimeFormatRFC3339
+)
+
+// DurationFormat selects how time.Duration values are represented.
+type DurationFormat = common.DurationFormat
+
+const (
+	// DurationFormatNanos is an integer of nanoseconds, which is the default.
+	DurationFormatNanos = common.DurationFormatNanos
+	// DurationFormatSeconds is an integer of seconds.
+	DurationFormatSeconds = common.DurationFormatSeconds
+	// DurationFormatMilli is an integer of milliseconds.
+	DurationFormatMilli = common.DurationFormatMilli
+	// DurationFormatFloat is a float of seconds.
+	DurationFormatFloat = common.DurationFormatFloat
+	// DurationFormatString is a string formatted by time.Duration.String.
+	DurationFormatString = common.DurationFormatString
+)
+
+// IntOverflow selects how DecodeOptions handle integers that do not fit.
+type IntOverflow = common.IntOverflow
+
+const (
+	// IntOverflowError fails with def.ErrValueOutOfRange.
+	IntOverflowError = common.IntOverflowError
+	// IntOverflowSaturate clamps the value to the nearest bound, such as
+	// math.MaxInt16 for an int16 or 0 for a negative value into a uint.
+	IntOverflowSaturate = common.IntOverflowSaturate
+	// IntOverflowWrap keeps the low bits like a Go conversion does.
+	// Floats outside of int64 still fail.
+	IntOverflowWrap = common.IntOverflowWrap
+)
+
+// FloatToInt selects how DecodeOptions handle floats decoded into integers.
+type FloatToInt = common.FloatToInt
+
+const (
+	// FloatToIntTruncate drops the fractional part.
+	FloatToIntTruncate = common.FloatToIntTruncate
+	// FloatToIntError fails with def.ErrFloatTruncated unless the float
+	// is a whole number.
+	FloatToIntError = common.FloatToIntError
+)
+
+// DefaultMaxDepth is the nesting depth limit used when
+// DecodeOptions.MaxDepth is zero, and by Unmarshal and UnmarshalRead.
+const DefaultMaxDepth = common.DefaultMaxDepth
+
+// Interner shares one allocation between equal decoded strings of up to
+// 64 bytes. It holds a bounded number of strings and is safe for
+// concurrent use.
+type Interner = common.Interner
+
+// NewInterner returns an Interner that holds at most maxSize strings.
+func NewInterner(maxSize int) *Interner {
+	return common.NewInterner(maxSize)
+}
+
+// Unmarshal analyzes the MessagePack-encoded data and stores
+// the result into the pointer of v.
+func (o DecodeOptions) Unmarshal(data []byte, v interface{}) error {
+	return decoding.DecodeWithOption(data, v, o.option())
+}
+
+// UnmarshalRead reads the MessagePack-encoded data from reader and stores
+// the result into the pointer of v.
+func (o DecodeOptions) UnmarshalRead(r io.Reader, v interface{}) error {
+	return streamdecoding.DecodeWithOption(r, v, o.option())
+}
+
+func (o DecodeOptions) option() common.DecodeOption {
+	return common.DecodeOption{
+		AsArray:  o.StructAsArray,
+		ZeroCopy: o.ZeroCopy,
+		Interner: o.Interner,
+		MaxDepth: o.MaxDepth,
+		Limits: common.Limits{
+			MaxStringLen:  o.MaxStringLen,
+			MaxBinLen:     o.MaxBinLen,
+			MaxArrayLen:   o.MaxArrayLen,
+			MaxMapLen:     o.MaxMapLen,
+			MaxTotalBytes: o.MaxTotalBytes,
+		},
+		ContinueOnError: o.ContinueOnError,
+		WeakTypes:       o.WeakTypes,
+		Narrowing: common.Narrowing{
+			IntOverflow: o.IntOverflow,
+			FloatToInt:  o.FloatToInt,
+		},
+		TimeFormat:     o.TimeFormat,
+		DurationFormat: o.DurationFormat,
+	}
+}
@@ -0,0 +1,1081 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/shamaton/msgpack/v3"
+	"github.com/shamaton/msgpack/v3/def"
+	tu "github.com/shamaton/msgpack/v3/internal/common/testutil"
+)
+
+func marshalBoth(t *testing.T, o msgpack.EncodeOptions, v any) []byte {
+	t.Helper()
+
+	b, err := o.Marshal(v)
+	tu.NoError(t, err)
+
+	buf := bytes.Buffer{}
+	err = o.MarshalWrite(&buf, v)
+	tu.NoError(t, err)
+	tu.EqualSlice(t, buf.Bytes(), b)
+	return b
+}
+
+func TestCanonical(t *testing.T) {
+	o := msgpack.EncodeOptions{Canonical: true}
+
+	t.Run("MapKeysSorted", func(t *testing.T) {
+		v := map[string]int{"bb": 1, "a": 2, "c": 3, "aa": 4}
+		for i := 0; i < 10; i++ {
+			b := marshalBoth(t, o, v)
+			tu.EqualSlice(t, b, []byte{
+				0x84,
+				0xa1, 'a', 0x02,
+				0xa1, 'c', 0x03,
+				0xa2, 'a', 'a', 0x04,
+				0xa2, 'b', 'b', 0x01,
+			})
+			tu.NoError(t, msgpack.IsCanonical(b))
+		}
+	})
+	t.Run("IntKeys", func(t *testing.T) {
+		v := map[int]string{-1: "x", 200: "y", 1: "z"}
+		b := marshalBoth(t, o, v)
+		tu.EqualSlice(t, b, []byte{
+			0x83,
+			0x01, 0xa1, 'z',
+			0xcc, 0xc8, 0xa1, 'y',
+			0xff, 0xa1, 'x',
+		})
+		tu.NoError(t, msgpack.IsCanonical(b))
+	})
+	t.Run("Float", func(t *testing.T) {
+		b := marshalBoth(t, o, 1.5)
+		tu.EqualSlice(t, b, []byte{def.Float32, 0x3f, 0xc0, 0x00, 0x00})
+
+		b = marshalBoth(t, o, 0.1)
+		tu.Equal(t, b[0], def.Float64)
+
+		b = marshalBoth(t, o, math.NaN())
+		tu.EqualSlice(t, b, []byte{def.Float32, 0x7f, 0xc0, 0x00, 0x00})
+
+		b = marshalBoth(t, o, float32(math.Float32frombits(0x7fc00001)))
+		tu.EqualSlice(t, b, []byte{def.Float32, 0x7f, 0xc0, 0x00, 0x00})
+
+		var f float64
+		tu.NoError(t, msgpack.UnmarshalAsMap(marshalBoth(t, o, 1.5), &f))
+		tu.Equal(t, f, 1.5)
+	})
+	t.Run("FloatMapValues", func(t *testing.T) {
+		b := marshalBoth(t, o, map[string]float64{"a": 2})
+		tu.EqualSlice(t, b, []byte{0x81, 0xa1, 'a', def.Float32, 0x40, 0x00, 0x00, 0x00})
+	})
+	t.Run("StructFields", func(t *testing.T) {
+		type st struct {
+			Bbb int
+			A   int
+			Cc  int
+		}
+		b := marshalBoth(t, o, st{Bbb: 1, A: 2, Cc: 3})
+		tu.EqualSlice(t, b, []byte{
+			0x83,
+			0xa1, 'A', 0x02,
+			0xa2, 'C', 'c', 0x03,
+			0xa3, 'B', 'b', 'b', 0x01,
+		})
+		tu.NoError(t, msgpack.IsCanonical(b))
+
+		var r st
+		tu.NoError(t, msgpack.UnmarshalAsMap(b, &r))
+		tu.Equal(t, r, st{Bbb: 1, A: 2, Cc: 3})
+	})
+	t.Run("DuplicateKeys", func(t *testing.T) {
+		v := map[any]int{int8(1): 1, uint64(1): 2}
+		_, err := o.Marshal(v)
+		tu.IsError(t, err, def.ErrNotCanonical)
+		err = o.MarshalWrite(&bytes.Buffer{}, v)
+		tu.IsError(t, err, def.ErrNotCanonical)
+	})
+	t.Run("Nested", func(t *testing.T) {
+		v := map[string]any{"z": []any{map[string]int{"y": 1, "x": 2}}, "a": nil}
+		b := marshalBoth(t, o, v)
+		tu.NoError(t, msgpack.IsCanonical(b))
+	})
+}
+
+func TestIsCanonical(t *testing.T) {
+	testcases := []struct {
+		name   string
+		data   []byte
+		offset string
+	}{
+		{name: "Uint8", data: []byte{def.Uint8, 0x01}, offset: "offset 0"},
+		{name: "Uint16", data: []byte{def.Uint16, 0x00, 0xff}, offset: "offset 0"},
+		{name: "PositiveInt8", data: []byte{def.Int8, 0x01}, offset: "offset 0"},
+		{name: "Int16", data: []byte{def.Int16, 0xff, 0x80}, offset: "offset 0"},
+		{name: "Float64", data: []byte{def.Float64, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}, offset: "offset 0"},
+		{name: "NaN32", data: []byte{def.Float32, 0x7f, 0xc0, 0x00, 0x01}, offset: "offset 0"},
+		{name: "Str8", data: []byte{def.Str8, 0x01, 'a'}, offset: "offset 0"},
+		{name: "Array16", data: []byte{def.Array16, 0x00, 0x00}, offset: "offset 0"},
+		{name: "Map16", data: []byte{def.Map16, 0x00, 0x00}, offset: "offset 0"},
+		{name: "Ext8", data: []byte{def.Ext8, 0x01, 0x01, 0x00}, offset: "offset 0"},
+		{name: "NestedElement", data: []byte{0x92, 0x01, def.Uint8, 0x02}, offset: "offset 2"},
+		{name: "UnsortedKeys", data: []byte{0x82, 0xa1, 'b', 0x01, 0xa1, 'a', 0x02}, offset: "offset 4"},
+		{name: "DuplicateKeys", data: []byte{0x82, 0xa1, 'a', 0x01, 0xa1, 'a', 0x02}, offset: "offset 4"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := msgpack.IsCanonical(tc.data)
+			tu.IsError(t, err, def.ErrNotCanonical)
+			tu.ErrorContains(t, err, tc.offset)
+			if !errors.Is(err, msgpack.Error) {
+				t.Fatal("error should wrap msgpack.Error")
+			}
+		})
+	}
+
+	t.Run("Canonical", func(t *testing.T) {
+		for _, data := range [][]byte{
+			{def.Uint8, 0x80},
+			{def.Int8, 0xdf},
+			{def.Fixext1, 0x01, 0x00},
+			{def.Ext8, 0x03, 0x01, 0x00, 0x00, 0x00},
+			{0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0x02},
+		} {
+			tu.NoError(t, msgpack.IsCanonical(data))
+		}
+	})
+	t.Run("LeftOver", func(t *testing.T) {
+		tu.IsError(t, msgpack.IsCanonical([]byte{0x01, 0x02}), def.ErrHasLeftOver)
+	})
+	t.Run("NoData", func(t *testing.T) {
+		tu.IsError(t, msgpack.IsCanonical(nil), def.ErrNoData)
+	})
+}
+
+func TestCompactFloats(t *testing.T) {
+	o := msgpack.EncodeOptions{CompactFloats: true}
+
+	for _, v := range []float64{0.5, 1.25, -2, math.Inf(1), math.MaxFloat32} {
+		b := marshalBoth(t, o, v)
+		tu.Equal(t, len(b), 5)
+		tu.Equal(t, b[0], def.Float32)
+
+		var r float64
+		tu.NoError(t, msgpack.Unmarshal(b, &r))
+		tu.Equal(t, r, v)
+	}
+	for _, v := range []float64{0.1, math.MaxFloat64, math.SmallestNonzeroFloat64} {
+		b := marshalBoth(t, o, v)
+		tu.Equal(t, len(b), 9)
+		tu.Equal(t, b[0], def.Float64)
+	}
+
+	b := marshalBoth(t, o, math.NaN())
+	tu.Equal(t, b[0], def.Float64)
+
+	b = marshalBoth(t, o, []float64{0.5, 0.1})
+	tu.EqualSlice(t, b[:6], []byte{0x92, def.Float32, 0x3f, 0x00, 0x00, 0x00})
+	tu.Equal(t, b[6], def.Float64)
+
+	b = marshalBoth(t, o, map[string]float64{"a": 0.5})
+	tu.EqualSlice(t, b, []byte{0x81, 0xa1, 'a', def.Float32, 0x3f, 0x00, 0x00, 0x00})
+}
+
+func TestFloatAsInt(t *testing.T) {
+	o := msgpack.EncodeOptions{FloatAsInt: true}
+
+	testcases := []struct {
+		name     string
+		v        any
+		expected []byte
+	}{
+		{name: "Zero", v: 0.0, expected: []byte{0x00}},
+		{name: "Positive", v: 3.0, expected: []byte{0x03}},
+		{name: "Negative", v: float32(-200), expected: []byte{def.Int16, 0xff, 0x38}},
+		{name: "Large", v: 1e15, expected: []byte{def.Uint64, 0x00, 0x03, 0x8d, 0x7e, 0xa4, 0xc6, 0x80, 0x00}},
+		{name: "Fraction", v: 0.5, expected: []byte{def.Float64, 0x3f, 0xe0, 0, 0, 0, 0, 0, 0}},
+		{name: "NegativeZero", v: math.Copysign(0, -1), expected: []byte{def.Float64, 0x80, 0, 0, 0, 0, 0, 0, 0}},
+		{name: "OutOfRange", v: float32(1e19), expected: []byte{def.Float32, 0x5f, 0x0a, 0xc7, 0x23}},
+		{name: "Slice", v: []float32{1, 2}, expected: []byte{0x92, 0x01, 0x02}},
+		{name: "Map", v: map[float64]float32{1: 2}, expected: []byte{0x81, 0x01, 0x02}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := marshalBoth(t, o, tc.v)
+			tu.EqualSlice(t, b, tc.expected)
+		})
+	}
+
+	t.Run("Decode", func(t *testing.T) {
+		b := marshalBoth(t, o, []float64{-7, 1.5})
+		var r []float64
+		tu.NoError(t, msgpack.Unmarshal(b, &r))
+		tu.EqualSlice(t, r, []float64{-7, 1.5})
+	})
+	t.Run("CompactFloats", func(t *testing.T) {
+		o := msgpack.EncodeOptions{FloatAsInt: true, CompactFloats: true}
+		b := marshalBoth(t, o, []float64{2, 0.5})
+		tu.EqualSlice(t, b, []byte{0x92, 0x02, def.Float32, 0x3f, 0x00, 0x00, 0x00})
+	})
+}
+
+func TestFixedWidthInts(t *testing.T) {
+	o := msgpack.EncodeOptions{FixedWidthInts: true}
+
+	testcases := []struct {
+		name     string
+		v        any
+		expected []byte
+	}{
+		{name: "Int8", v: int8(5), expected: []byte{def.Int8, 0x05}},
+		{name: "Int16", v: int16(-1), expected: []byte{def.Int16, 0xff, 0xff}},
+		{name: "Int32", v: int32(5), expected: []byte{def.Int32, 0, 0, 0, 0x05}},
+		{name: "Int64", v: int64(5), expected: []byte{def.Int64, 0, 0, 0, 0, 0, 0, 0, 0x05}},
+		{name: "Uint8", v: uint8(5), expected: []byte{def.Uint8, 0x05}},
+		{name: "Uint16", v: uint16(5), expected: []byte{def.Uint16, 0, 0x05}},
+		{name: "Uint32", v: uint32(5), expected: []byte{def.Uint32, 0, 0, 0, 0x05}},
+		{name: "Uint64", v: uint64(5), expected: []byte{def.Uint64, 0, 0, 0, 0, 0, 0, 0, 0x05}},
+		{name: "Slice", v: []int16{1, 2}, expected: []byte{0x92, def.Int16, 0, 0x01, def.Int16, 0, 0x02}},
+		{name: "Map", v: map[string]uint8{"a": 1}, expected: []byte{0x81, 0xa1, 'a', def.Uint8, 0x01}},
+		{name: "Struct", v: struct{ A int32 }{A: 1}, expected: []byte{0x81, 0xa1, 'A', def.Int32, 0, 0, 0, 0x01}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := marshalBoth(t, o, tc.v)
+			tu.EqualSlice(t, b, tc.expected)
+		})
+	}
+
+	t.Run("Decode", func(t *testing.T) {
+		v := []int64{math.MinInt64, -1, 0, math.MaxInt64}
+		b := marshalBoth(t, o, v)
+		var r []int64
+		tu.NoError(t, msgpack.Unmarshal(b, &r))
+		tu.EqualSlice(t, r, v)
+	})
+	t.Run("Canonical", func(t *testing.T) {
+		o := msgpack.EncodeOptions{FixedWidthInts: true, Canonical: true}
+		b := marshalBoth(t, o, int64(5))
+		tu.EqualSlice(t, b, []byte{0x05})
+	})
+}
+
+func TestZeroCopy(t *testing.T) {
+	type st struct {
+		S  string
+		B  []byte
+		SB []byte
+	}
+	v := st{S: "hello", B: []byte{1, 2, 3}, SB: []byte("abc")}
+	b, err := msgpack.MarshalAsMap(v)
+	tu.NoError(t, err)
+
+	t.Run("Copy", func(t *testing.T) {
+		data := append([]byte{}, b...)
+		var r st
+		tu.NoError(t, msgpack.DecodeOptions{}.Unmarshal(data, &r))
+		tu.Equal(t, r.S, "hello")
+		tu.EqualSlice(t, r.B, []byte{1, 2, 3})
+
+		i := bytes.Index(data, []byte("hello"))
+		data[i] = 'j'
+		tu.Equal(t, r.S, "hello")
+		tu.EqualSlice(t, r.SB, []byte("abc"))
+	})
+	t.Run("ZeroCopy", func(t *testing.T) {
+		data := append([]byte{}, b...)
+		var r st
+		tu.NoError(t, msgpack.DecodeOptions{ZeroCopy: true}.Unmarshal(data, &r))
+		tu.Equal(t, r.S, "hello")
+		tu.EqualSlice(t, r.B, []byte{1, 2, 3})
+		tu.EqualSlice(t, r.SB, []byte("abc"))
+
+		i := bytes.Index(data, []byte("hello"))
+		data[i] = 'j'
+		tu.Equal(t, r.S, "jello")
+	})
+	t.Run("Bytes", func(t *testing.T) {
+		// []byte values share memory with the input in both modes
+		for _, o := range []msgpack.DecodeOptions{{}, {ZeroCopy: true}} {
+			data := append([]byte{}, b...)
+			var r st
+			tu.NoError(t, o.Unmarshal(data, &r))
+			r.B[0] = 9
+			tu.Equal(t, bytes.Contains(data, []byte{9, 2, 3}), true)
+		}
+	})
+	t.Run("Interface", func(t *testing.T) {
+		data, err := msgpack.Marshal([]any{"a", []byte{1}})
+		tu.NoError(t, err)
+
+		var r []any
+		tu.NoError(t, msgpack.DecodeOptions{ZeroCopy: true}.Unmarshal(data, &r))
+		tu.Equal(t, r[0].(string), "a")
+		r[1].([]byte)[0] = 7
+		tu.Equal(t, data[len(data)-1], byte(7))
+	})
+	t.Run("Stream", func(t *testing.T) {
+		var r st
+		tu.NoError(t, msgpack.DecodeOptions{ZeroCopy: true}.UnmarshalRead(bytes.NewReader(b), &r))
+		tu.Equal(t, r, st{S: "hello", B: []byte{1, 2, 3}, SB: []byte("abc")})
+	})
+}
+
+func TestInterner(t *testing.T) {
+	data, err := msgpack.Marshal([]map[string]any{
+		{"name": "a", "kind": "enum"},
+		{"name": "b", "kind": "enum"},
+	})
+	tu.NoError(t, err)
+
+	for _, z := range []bool{false, true} {
+		in := msgpack.NewInterner(100)
+		o := msgpack.DecodeOptions{Interner: in, ZeroCopy: z}
+
+		var r1, r2 []map[string]any
+		tu.NoError(t, o.Unmarshal(data, &r1))
+		tu.NoError(t, o.UnmarshalRead(bytes.NewReader(data), &r2))
+		tu.Equal(t, r1[1]["name"].(string), "b")
+		tu.Equal(t, r2[0]["kind"].(string), "enum")
+		tu.Equal(t, in.Len(), 5)
+
+		k1 := r1[0]["kind"].(string)
+		k2 := r2[1]["kind"].(string)
+		tu.Equal(t, unsafe.StringData(k1), unsafe.StringData(k2))
+	}
+
+	t.Run("Struct", func(t *testing.T) {
+		type st struct {
+			A string
+			M map[string]int
+		}
+		data, err := msgpack.MarshalAsMap(st{A: "x", M: map[string]int{"x": 1}})
+		tu.NoError(t, err)
+
+		in := msgpack.NewInterner(1)
+		var r st
+		tu.NoError(t, msgpack.DecodeOptions{Interner: in}.Unmarshal(data, &r))
+		tu.Equal(t, r.A, "x")
+		tu.Equal(t, r.M["x"], 1)
+		tu.Equal(t, in.Len(), 1)
+	})
+}
+
+func TestMaxDepth(t *testing.T) {
+	nested := func(depth int) []byte {
+		b := bytes.Repeat([]byte{0x91}, depth)
+		return append(b, 0x01)
+	}
+	decode := func(o msgpack.DecodeOptions, data []byte, v any) []error {
+		return []error{
+			o.Unmarshal(data, v),
+			o.UnmarshalRead(bytes.NewReader(data), v),
+		}
+	}
+
+	t.Run("Default", func(t *testing.T) {
+		data := nested(msgpack.DefaultMaxDepth + 1)
+		var v any
+		tu.IsError(t, msgpack.Unmarshal(data, &v), def.ErrMaxDepthExceeded)
+		tu.IsError(t, msgpack.UnmarshalRead(bytes.NewReader(data), &v), def.ErrMaxDepthExceeded)
+
+		data = nested(msgpack.DefaultMaxDepth)
+		tu.NoError(t, msgpack.Unmarshal(data, &v))
+		tu.NoError(t, msgpack.UnmarshalRead(bytes.NewReader(data), &v))
+	})
+
+	o := msgpack.DecodeOptions{MaxDepth: 3}
+	t.Run("Interface", func(t *testing.T) {
+		var v any
+		for _, err := range decode(o, nested(3), &v) {
+			tu.NoError(t, err)
+		}
+		for _, err := range decode(o, nested(4), &v) {
+			tu.IsError(t, err, def.ErrMaxDepthExceeded)
+		}
+		for _, err := range decode(o, []byte{0x81, 0x01, 0x81, 0x01, 0x81, 0x01, 0x81, 0x01, 0x01}, &v) {
+			tu.IsError(t, err, def.ErrMaxDepthExceeded)
+		}
+	})
+	t.Run("Typed", func(t *testing.T) {
+		// the innermost []int and map[int]int are not counted
+		var v [][][][][]int
+		for _, err := range decode(o, nested(5), &v) {
+			tu.IsError(t, err, def.ErrMaxDepthExceeded)
+		}
+		var m map[int]map[int]map[int]map[int]map[int]int
+		for _, err := range decode(o, []byte{0x81, 0x01, 0x81, 0x01, 0x81, 0x01, 0x81, 0x01, 0x81, 0x01, 0x01}, &m) {
+			tu.IsError(t, err, def.ErrMaxDepthExceeded)
+		}
+	})
+	t.Run("ContinueOnError", func(t *testing.T) {
+		// a skipped value leaves the depth as it was
+		type st struct {
+			A any
+			B any
+		}
+		data := []byte{0x82, 0xa1, 'A', 0x91, 0xc1, 0xa1, 'B', 0x91, 0x91, 0x01}
+		var v st
+		err := msgpack.DecodeOptions{MaxDepth: 3, ContinueOnError: true}.Unmarshal(data, &v)
+		var errs msgpack.DecodeErrors
+		if !errors.As(err, &errs) {
+			t.Fatalf("not DecodeErrors: %v", err)
+		}
+		tu.Equal(t, len(errs), 1)
+		tu.Equal(t, errs[0].Path, "st.A[0]")
+		tu.Equal(t, v.B, any([]any{[]any{uint8(1)}}))
+	})
+	t.Run("Struct", func(t *testing.T) {
+		type node struct {
+			Next *node
+		}
+		data, err := msgpack.MarshalAsMap(node{Next: &node{Next: &node{Next: &node{}}}})
+		tu.NoError(t, err)
+		var v node
+		for _, err := range decode(o, data, &v) {
+			tu.IsError(t, err, def.ErrMaxDepthExceeded)
+		}
+		for _, err := range decode(msgpack.DecodeOptions{MaxDepth: 4}, data, &v) {
+			tu.NoError(t, err)
+		}
+	})
+	t.Run("Skip", func(t *testing.T) {
+		type st struct {
+			A int
+		}
+		data := append([]byte{0x82, 0xa1, 'A', 0x01, 0xa1, 'B'}, nested(3)...)
+		var v st
+		for _, err := range decode(o, data, &v) {
+			tu.IsError(t, err, def.ErrMaxDepthExceeded)
+		}
+		for _, err := range decode(msgpack.DecodeOptions{MaxDepth: 4}, data, &v) {
+			tu.NoError(t, err)
+		}
+	})
+	t.Run("IsCanonical", func(t *testing.T) {
+		tu.IsError(t, msgpack.IsCanonical(nested(msgpack.DefaultMaxDepth+1)), def.ErrMaxDepthExceeded)
+	})
+}
+
+func TestLimits(t *testing.T) {
+	decode := func(o msgpack.DecodeOptions, data []byte, v any) []error {
+		return []error{
+			o.Unmarshal(data, v),
+			o.UnmarshalRead(bytes.NewReader(data), v),
+		}
+	}
+
+	testcases := []struct {
+		name     string
+		o        msgpack.DecodeOptions
+		data     []byte
+		v        func() any
+		expected error
+	}{
+		{
+			name:     "String",
+			o:        msgpack.DecodeOptions{MaxStringLen: 2},
+			data:     []byte{0xa3, 'a', 'b', 'c'},
+			v:        func() any { return new(string) },
+			expected: def.ErrMaxStringLenExceeded,
+		},
+		{
+			name:     "Str32Header",
+			o:        msgpack.DecodeOptions{MaxStringLen: 1024},
+			data:     []byte{def.Str32, 0xff, 0xff, 0xff, 0xff},
+			v:        func() any { return new(any) },
+			expected: def.ErrMaxStringLenExceeded,
+		},
+		{
+			name:     "StringToBytes",
+			o:        msgpack.DecodeOptions{MaxStringLen: 2},
+			data:     []byte{0xa3, 'a', 'b', 'c'},
+			v:        func() any { return new([]byte) },
+			expected: def.ErrMaxStringLenExceeded,
+		},
+		{
+			name:     "Bin",
+			o:        msgpack.DecodeOptions{MaxBinLen: 2},
+			data:     []byte{def.Bin8, 0x03, 1, 2, 3},
+			v:        func() any { return new([]byte) },
+			expected: def.ErrMaxBinLenExceeded,
+		},
+		{
+			name:     "Bin32Header",
+			o:        msgpack.DecodeOptions{MaxBinLen: 1024},
+			data:     []byte{def.Bin32, 0xff, 0xff, 0xff, 0xff},
+			v:        func() any { return new(any) },
+			expected: def.ErrMaxBinLenExceeded,
+		},
+		{
+			name:     "Array",
+			o:        msgpack.DecodeOptions{MaxArrayLen: 2},
+			data:     []byte{0x93, 1, 2, 3},
+			v:        func() any { return new([]int) },
+			expected: def.ErrMaxArrayLenExceeded,
+		},
+		{
+			name:     "Array32Header",
+			o:        msgpack.DecodeOptions{MaxArrayLen: 1024},
+			data:     []byte{def.Array32, 0xff, 0xff, 0xff, 0xff},
+			v:        func() any { return new(any) },
+			expected: def.ErrMaxArrayLenExceeded,
+		},
+		{
+			name:     "Map",
+			o:        msgpack.DecodeOptions{MaxMapLen: 1},
+			data:     []byte{0x82, 1, 1, 2, 2},
+			v:        func() any { return new(map[int]int) },
+			expected: def.ErrMaxMapLenExceeded,
+		},
+		{
+			name:     "MapInterface",
+			o:        msgpack.DecodeOptions{MaxMapLen: 1},
+			data:     []byte{0x82, 1, 1, 2, 2},
+			v:        func() any { return new(any) },
+			expected: def.ErrMaxMapLenExceeded,
+		},
+		{
+			name:     "TotalBytes",
+			o:        msgpack.DecodeOptions{MaxTotalBytes: 4},
+			data:     []byte{0x94, 1, 2, 3, 4},
+			v:        func() any { return new([]int) },
+			expected: def.ErrMaxTotalBytesExceeded,
+		},
+		{
+			name:     "TotalBytesHeader",
+			o:        msgpack.DecodeOptions{MaxTotalBytes: 1024},
+			data:     append([]byte{def.Str16, 0x04, 0x01}, bytes.Repeat([]byte{'a'}, 1025)...),
+			v:        func() any { return new(string) },
+			expected: def.ErrMaxTotalBytesExceeded,
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, err := range decode(tc.o, tc.data, tc.v()) {
+				tu.IsError(t, err, tc.expected)
+				if !errors.Is(err, msgpack.Error) {
+					t.Fatal("error should wrap msgpack.Error")
+				}
+			}
+		})
+	}
+
+	t.Run("WithinLimits", func(t *testing.T) {
+		o := msgpack.DecodeOptions{
+			MaxStringLen:  3,
+			MaxBinLen:     3,
+			MaxArrayLen:   3,
+			MaxMapLen:     3,
+			MaxTotalBytes: 64,
+		}
+		data, err := msgpack.Marshal(map[string]any{"abc": []any{[]byte{1, 2, 3}, "xyz"}})
+		tu.NoError(t, err)
+		var v map[string]any
+		for _, err := range decode(o, data, &v) {
+			tu.NoError(t, err)
+		}
+	})
+}
+
+type cycleNode struct {
+	V    int
+	Next *cycleNode
+}
+
+func TestCycleDepth(t *testing.T) {
+	encode := func(o msgpack.EncodeOptions, v any) []error {
+		_, err1 := o.Marshal(v)
+		err2 := o.MarshalWrite(&bytes.Buffer{}, v)
+		return []error{err1, err2}
+	}
+
+	self := &cycleNode{V: 1}
+	self.Next = self
+	m := map[string]any{}
+	m["self"] = m
+	s := make([]any, 1)
+	s[0] = s
+
+	testcases := []struct {
+		name string
+		v    any
+		typ  string
+	}{
+		{name: "Pointer", v: self, typ: "*msgpack_test.cycleNode"},
+		{name: "Map", v: m, typ: "map[string]interface {}"},
+		{name: "Slice", v: s, typ: "[]interface {}"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, o := range []msgpack.EncodeOptions{{}, {CycleDepth: -1}, {CycleDepth: 3}, {Canonical: true}} {
+				for _, err := range encode(o, tc.v) {
+					tu.IsError(t, err, def.ErrCycle)
+					tu.ErrorContains(t, err, "via "+tc.typ)
+				}
+			}
+		})
+	}
+
+	t.Run("Path", func(t *testing.T) {
+		for _, err := range encode(msgpack.EncodeOptions{CycleDepth: -1}, self) {
+			var ee *msgpack.EncodeError
+			if !errors.As(err, &ee) {
+				t.Fatalf("not an EncodeError: %v", err)
+			}
+			// the top-level pointer is dereferenced before tracking starts
+			tu.Equal(t, ee.Path, "cycleNode.Next.Next")
+		}
+
+		// cycles found after the default depth keep the ends of the path
+		for _, err := range encode(msgpack.EncodeOptions{}, self) {
+			var ee *msgpack.EncodeError
+			if !errors.As(err, &ee) {
+				t.Fatalf("not an EncodeError: %v", err)
+			}
+			tu.Equal(t, strings.Count(ee.Path, ".Next"), 64)
+			tu.ErrorContains(t, ee, "elements)")
+		}
+	})
+
+	t.Run("Acyclic", func(t *testing.T) {
+		// deeper than the default depth, and sharing a pointer is not a cycle
+		var head *cycleNode
+		for i := 0; i < msgpack.DefaultCycleDepth+10; i++ {
+			head = &cycleNode{V: i, Next: head}
+		}
+		shared := &cycleNode{V: 1}
+		v := []*cycleNode{head, shared, shared}
+		for _, o := range []msgpack.EncodeOptions{{}, {CycleDepth: -1}} {
+			b := marshalBoth(t, o, v)
+			var r []*cycleNode
+			tu.NoError(t, msgpack.UnmarshalAsMap(b, &r))
+			tu.Equal(t, r[2].V, 1)
+		}
+	})
+}
+
+func TestWeakTypes(t *testing.T) {
+	o := msgpack.DecodeOptions{WeakTypes: true}
+	decode := func(t *testing.T, o msgpack.DecodeOptions, in any, typ reflect.Type) []any {
+		t.Helper()
+		data, err := msgpack.Marshal(in)
+		tu.NoError(t, err)
+
+		v1, v2 := reflect.New(typ), reflect.New(typ)
+		err1 := o.Unmarshal(data, v1.Interface())
+		err2 := o.UnmarshalRead(bytes.NewReader(data), v2.Interface())
+		if err1 != nil || err2 != nil {
+			return []any{err1, err2}
+		}
+		return []any{v1.Elem().Interface(), v2.Elem().Interface()}
+	}
+
+	type weakStruct struct {
+		ID     int
+		Price  float64
+		Active bool
+		Code   string
+		Tags   []string
+	}
+
+	testcases := []struct {
+		name     string
+		in       any
+		expected any
+	}{
+		{name: "StringToInt", in: "42", expected: 42},
+		{name: "StringToNegativeInt8", in: "-8", expected: int8(-8)},
+		{name: "WholeFloatStringToInt", in: "3.0", expected: int64(3)},
+		{name: "StringToUint", in: "7", expected: uint16(7)},
+		{name: "StringToFloat", in: "1.25", expected: 1.25},
+		{name: "StringToFloat32", in: "0.5", expected: float32(0.5)},
+		{name: "UintToString", in: 42, expected: "42"},
+		{name: "IntToString", in: -3, expected: "-3"},
+		{name: "FloatToString", in: 1.5, expected: "1.5"},
+		{name: "Float32ToString", in: float32(0.1), expected: "0.1"},
+		{name: "OneToBool", in: 1, expected: true},
+		{name: "ZeroToBool", in: 0, expected: false},
+		{name: "FloatToInt", in: 3.0, expected: 3},
+		{name: "ValueToSlice", in: 5, expected: []int{5}},
+		{name: "StringToSlice", in: "a", expected: []string{"a"}},
+		{name: "StringToIntSlice", in: []any{"1", 2}, expected: []int{1, 2}},
+		{name: "MapKeys", in: map[string]any{"1": "2"}, expected: map[int]int{1: 2}},
+		{name: "Struct", in: map[string]any{
+			"ID": "12", "Price": "9.5", "Active": 1, "Code": 7, "Tags": "x",
+		}, expected: weakStruct{ID: 12, Price: 9.5, Active: true, Code: "7", Tags: []string{"x"}}},
+		{name: "Unchanged", in: []any{1, "a"}, expected: []any{uint8(1), "a"}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, v := range decode(t, o, tc.in, reflect.TypeOf(tc.expected)) {
+				tu.Equal(t, v, tc.expected)
+			}
+		})
+	}
+
+	errcases := []struct {
+		name     string
+		in       any
+		typ      reflect.Type
+		expected error
+	}{
+		{name: "NotNumber", in: "4x", typ: reflect.TypeOf(0), expected: def.ErrCanNotDecode},
+		{name: "FractionToInt", in: "3.5", typ: reflect.TypeOf(0), expected: def.ErrCanNotDecode},
+		{name: "IntOutOfRange", in: "300", typ: reflect.TypeOf(int8(0)), expected: def.ErrValueOutOfRange},
+		{name: "NegativeToUint", in: "-1", typ: reflect.TypeOf(uint(0)), expected: def.ErrValueOutOfRange},
+		{name: "TwoToBool", in: 2, typ: reflect.TypeOf(false), expected: def.ErrValueOutOfRange},
+		{name: "BoolToString", in: true, typ: reflect.TypeOf(""), expected: def.ErrCanNotDecode},
+	}
+	for _, tc := range errcases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, v := range decode(t, o, tc.in, tc.typ) {
+				err, _ := v.(error)
+				tu.IsError(t, err, tc.expected)
+			}
+		})
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		for _, v := range decode(t, msgpack.DecodeOptions{}, "42", reflect.TypeOf(0)) {
+			err, _ := v.(error)
+			tu.IsError(t, err, def.ErrCanNotDecode)
+		}
+	})
+}
+
+func TestNarrowing(t *testing.T) {
+	decode := func(t *testing.T, o msgpack.DecodeOptions, in any, typ reflect.Type) []any {
+		t.Helper()
+		data, err := msgpack.Marshal(in)
+		tu.NoError(t, err)
+
+		v1, v2 := reflect.New(typ), reflect.New(typ)
+		err1 := o.Unmarshal(data, v1.Interface())
+		err2 := o.UnmarshalRead(bytes.NewReader(data), v2.Interface())
+		if err1 != nil || err2 != nil {
+			return []any{err1, err2}
+		}
+		return []any{v1.Elem().Interface(), v2.Elem().Interface()}
+	}
+
+	saturate := msgpack.DecodeOptions{IntOverflow: msgpack.IntOverflowSaturate}
+	wrap := msgpack.DecodeOptions{IntOverflow: msgpack.IntOverflowWrap}
+	exact := msgpack.DecodeOptions{FloatToInt: msgpack.FloatToIntError}
+
+	testcases := []struct {
+		name     string
+		o        msgpack.DecodeOptions
+		in       any
+		expected any
+	}{
+		{name: "SaturateMax", o: saturate, in: 40000, expected: int16(math.MaxInt16)},
+		{name: "SaturateMin", o: saturate, in: -40000, expected: int16(math.MinInt16)},
+		{name: "SaturateUint", o: saturate, in: 300, expected: uint8(math.MaxUint8)},
+		{name: "SaturateNegativeUint", o: saturate, in: -1, expected: uint32(0)},
+		{name: "SaturateUint64ToInt64", o: saturate, in: uint64(math.MaxUint64), expected: int64(math.MaxInt64)},
+		{name: "SaturateFloat", o: saturate, in: 1e30, expected: int64(math.MaxInt64)},
+		{name: "SaturateSlice", o: saturate, in: []int{1, 200, -200}, expected: []int8{1, math.MaxInt8, math.MinInt8}},
+		{name: "SaturateMap", o: saturate, in: map[string]int{"a": 70000}, expected: map[string]uint16{"a": math.MaxUint16}},
+		{name: "WrapInt", o: wrap, in: 300, expected: int8(44)},
+		{name: "WrapUint", o: wrap, in: 258, expected: uint8(2)},
+		{name: "WrapNegativeUint", o: wrap, in: -1, expected: uint16(math.MaxUint16)},
+		{name: "WrapUint64ToInt64", o: wrap, in: uint64(math.MaxUint64), expected: int64(-1)},
+		{name: "WrapSlice", o: wrap, in: []int{1, 256}, expected: []uint8{1, 0}},
+		{name: "TruncateFloat", in: 2.75, expected: 2},
+		{name: "WholeFloat", o: exact, in: 3.0, expected: 3},
+		{name: "InRange", o: saturate, in: 5, expected: int8(5)},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, v := range decode(t, tc.o, tc.in, reflect.TypeOf(tc.expected)) {
+				tu.Equal(t, v, tc.expected)
+			}
+		})
+	}
+
+	errcases := []struct {
+		name     string
+		o        msgpack.DecodeOptions
+		in       any
+		typ      reflect.Type
+		expected error
+	}{
+		{name: "Default", in: 300, typ: reflect.TypeOf(int8(0)), expected: def.ErrValueOutOfRange},
+		{name: "DefaultSlice", in: []int{300}, typ: reflect.TypeOf([]int8{}), expected: def.ErrValueOutOfRange},
+		{name: "FractionalFloat", o: exact, in: 2.5, typ: reflect.TypeOf(0), expected: def.ErrFloatTruncated},
+		{name: "FractionalFloatSlice", o: exact, in: []float64{2.5}, typ: reflect.TypeOf([]int{}), expected: def.ErrFloatTruncated},
+		{name: "WrapFloat", o: wrap, in: 1e30, typ: reflect.TypeOf(int64(0)), expected: def.ErrValueOutOfRange},
+		{name: "SaturateNaN", o: saturate, in: math.NaN(), typ: reflect.TypeOf(0), expected: def.ErrValueOutOfRange},
+	}
+	for _, tc := range errcases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, v := range decode(t, tc.o, tc.in, tc.typ) {
+				err, _ := v.(error)
+				tu.IsError(t, err, tc.expected)
+			}
+		})
+	}
+}
+
+func TestTimeFormat(t *testing.T) {
+	decode := func(t *testing.T, o msgpack.DecodeOptions, data []byte, v any) {
+		t.Helper()
+		tu.NoError(t, o.Unmarshal(data, v))
+		rv := reflect.ValueOf(v).Elem()
+		w := reflect.New(rv.Type())
+		tu.NoError(t, o.UnmarshalRead(bytes.NewReader(data), w.Interface()))
+		tu.Equal(t, reflect.DeepEqual(w.Elem().Interface(), rv.Interface()), true)
+	}
+
+	in := time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	inMilli := in.Truncate(time.Millisecond)
+	inSec := in.Truncate(time.Second)
+
+	testcases := []struct {
+		name     string
+		format   msgpack.TimeFormat
+		encoded  any
+		expected time.Time
+	}{
+		{name: "Unix", format: msgpack.TimeFormatUnix, encoded: in.Unix(), expected: inSec},
+		{name: "UnixMilli", format: msgpack.TimeFormatUnixMilli, encoded: in.UnixMilli(), expected: inMilli},
+		{name: "UnixFloat", format: msgpack.TimeFormatUnixFloat, encoded: float64(in.Unix()) + 0.123456789, expected: in},
+		{name: "RFC3339", format: msgpack.TimeFormatRFC3339, encoded: "2024-01-02T03:04:05.123456789Z", expected: in},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := marshalBoth(t, msgpack.EncodeOptions{TimeFormat: tc.format}, in)
+			expected, err := msgpack.Marshal(tc.encoded)
+			tu.NoError(t, err)
+			tu.EqualSlice(t, data, expected)
+
+			var v time.Time
+			decode(t, msgpack.DecodeOptions{TimeFormat: tc.format}, data, &v)
+			if tc.format == msgpack.TimeFormatUnixFloat {
+				tu.Equal(t, v.Sub(tc.expected).Abs() < time.Microsecond, true)
+			} else {
+				tu.Equal(t, v.Equal(tc.expected), true)
+			}
+			tu.Equal(t, v.Location(), time.UTC)
+
+			// the default format keeps the timestamp extension
+			var ts time.Time
+			data = marshalBoth(t, msgpack.EncodeOptions{}, in)
+			decode(t, msgpack.DecodeOptions{TimeFormat: tc.format}, data, &ts)
+			tu.Equal(t, ts.Equal(in), true)
+		})
+	}
+
+	t.Run("AnyInput", func(t *testing.T) {
+		o := msgpack.DecodeOptions{TimeFormat: msgpack.TimeFormatUnix}
+		for _, encoded := range []any{in.Unix(), uint64(in.Unix()), float64(in.Unix()), in.Format(time.RFC3339)} {
+			data, err := msgpack.Marshal(encoded)
+			tu.NoError(t, err)
+			var v time.Time
+			decode(t, o, data, &v)
+			tu.Equal(t, v.Equal(inSec), true)
+		}
+	})
+
+	t.Run("RFC3339Offset", func(t *testing.T) {
+		data, err := msgpack.Marshal("2024-01-02T12:04:05+09:00")
+		tu.NoError(t, err)
+		var v time.Time
+		decode(t, msgpack.DecodeOptions{TimeFormat: msgpack.TimeFormatRFC3339}, data, &v)
+		_, offset := v.Zone()
+		tu.Equal(t, offset, 9*60*60)
+		tu.Equal(t, v.Equal(inSec), true)
+	})
+
+	type tagged struct {
+		Ms    time.Time   `msgpack:"ms,time=unixms"`
+		Str   time.Time   `msgpack:"str,time=rfc3339"`
+		Ext   time.Time   `msgpack:"ext,time=ext"`
+		Secs  []time.Time `msgpack:"secs,time=unix"`
+		Plain time.Time   `msgpack:"plain"`
+	}
+	value := tagged{Ms: inMilli, Str: in, Ext: in, Secs: []time.Time{inSec}, Plain: in}
+
+	t.Run("Tag", func(t *testing.T) {
+		for _, asArray := range []bool{false, true} {
+			o := msgpack.EncodeOptions{StructAsArray: asArray}
+			data := marshalBoth(t, o, value)
+			var v tagged
+			decode(t, msgpack.DecodeOptions{StructAsArray: asArray}, data, &v)
+			tu.Equal(t, v.Ms.Equal(value.Ms), true)
+			tu.Equal(t, v.Str.Equal(value.Str), true)
+			tu.Equal(t, v.Ext.Equal(value.Ext), true)
+			tu.Equal(t, len(v.Secs), 1)
+			tu.Equal(t, v.Secs[0].Equal(value.Secs[0]), true)
+			tu.Equal(t, v.Plain.Equal(value.Plain), true)
+
+			var raw map[string]any
+			if !asArray {
+				tu.NoError(t, msgpack.Unmarshal(data, &raw))
+				tu.Equal(t, raw["ms"], any(uint64(inMilli.UnixMilli())))
+				tu.Equal(t, raw["str"], any(in.Format(time.RFC3339Nano)))
+				_, isTime := raw["plain"].(time.Time)
+				tu.Equal(t, isTime, true)
+			}
+		}
+	})
+
+	t.Run("TagOverridesOption", func(t *testing.T) {
+		data := marshalBoth(t, msgpack.EncodeOptions{TimeFormat: msgpack.TimeFormatRFC3339}, value)
+		var raw map[string]any
+		tu.NoError(t, msgpack.Unmarshal(data, &raw))
+		_, isTime := raw["ext"].(time.Time)
+		tu.Equal(t, isTime, true)
+		_, isString := raw["plain"].(string)
+		tu.Equal(t, isString, true)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		o := msgpack.DecodeOptions{TimeFormat: msgpack.TimeFormatRFC3339}
+		data, err := msgpack.Marshal("yesterday")
+		tu.NoError(t, err)
+		var v time.Time
+		tu.IsError(t, o.Unmarshal(data, &v), def.ErrCanNotDecode)
+		tu.IsError(t, o.UnmarshalRead(bytes.NewReader(data), &v), def.ErrCanNotDecode)
+
+		data, err = msgpack.Marshal(math.Inf(1))
+		tu.NoError(t, err)
+		tu.IsError(t, o.Unmarshal(data, &v), def.ErrValueOutOfRange)
+		tu.IsError(t, o.UnmarshalRead(bytes.NewReader(data), &v), def.ErrValueOutOfRange)
+	})
+}
+
+func TestDurationFormat(t *testing.T) {
+	decode := func(t *testing.T, o msgpack.DecodeOptions, data []byte, v any) {
+		t.Helper()
+		tu.NoError(t, o.Unmarshal(data, v))
+		rv := reflect.ValueOf(v).Elem()
+		w := reflect.New(rv.Type())
+		tu.NoError(t, o.UnmarshalRead(bytes.NewReader(data), w.Interface()))
+		tu.Equal(t, reflect.DeepEqual(w.Elem().Interface(), rv.Interface()), true)
+	}
+
+	in := 90*time.Minute + 1500*time.Millisecond + 7
+
+	testcases := []struct {
+		name     string
+		format   msgpack.DurationFormat
+		encoded  any
+		expected time.Duration
+	}{
+		{name: "Nanos", format: msgpack.DurationFormatNanos, encoded: int64(in), expected: in},
+		{name: "Seconds", format: msgpack.DurationFormatSeconds, encoded: int64(5401), expected: 5401 * time.Second},
+		{name: "Milli", format: msgpack.DurationFormatMilli, encoded: int64(5401500), expected: 5401500 * time.Millisecond},
+		{name: "Float", format: msgpack.DurationFormatFloat, encoded: in.Seconds(), expected: in},
+		{name: "String", format: msgpack.DurationFormatString, encoded: "1h30m1.500000007s", expected: in},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			data := marshalBoth(t, msgpack.EncodeOptions{DurationFormat: tc.format}, in)
+			expected, err := msgpack.Marshal(tc.encoded)
+			tu.NoError(t, err)
+			tu.EqualSlice(t, data, expected)
+
+			var v time.Duration
+			decode(t, msgpack.DecodeOptions{DurationFormat: tc.format}, data, &v)
+			tu.Equal(t, v, tc.expected)
+		})
+	}
+
+	t.Run("AnyInput", func(t *testing.T) {
+		o := msgpack.DecodeOptions{DurationFormat: msgpack.DurationFormatSeconds}
+		for _, encoded := range []any{int64(90), uint8(90), 90.0, float32(90), "1m30s"} {
+			data, err := msgpack.Marshal(encoded)
+			tu.NoError(t, err)
+			var v time.Duration
+			decode(t, o, data, &v)
+			tu.Equal(t, v, 90*time.Second)
+		}
+	})
+
+	type tagged struct {
+		Timeout  time.Duration   `msgpack:"timeout,duration=ms"`
+		Interval time.Duration   `msgpack:"interval,duration=string"`
+		Raw      time.Duration   `msgpack:"raw,duration=ns"`
+		Retries  []time.Duration `msgpack:"retries,duration=s"`
+		Plain    time.Duration   `msgpack:"plain"`
+	}
+	value := tagged{
+		Timeout:  1500 * time.Millisecond,
+		Interval: time.Hour,
+		Raw:      7,
+		Retries:  []time.Duration{time.Second, 2 * time.Second},
+		Plain:    3,
+	}
+
+	t.Run("Tag", func(t *testing.T) {
+		for _, asArray := range []bool{false, true} {
+			data := marshalBoth(t, msgpack.EncodeOptions{StructAsArray: asArray}, value)
+			var v tagged
+			decode(t, msgpack.DecodeOptions{StructAsArray: asArray}, data, &v)
+			tu.Equal(t, reflect.DeepEqual(v, value), true)
+
+			if !asArray {
+				var raw map[string]any
+				tu.NoError(t, msgpack.Unmarshal(data, &raw))
+				tu.Equal(t, raw["timeout"], any(uint16(1500)))
+				tu.Equal(t, raw["interval"], any("1h0m0s"))
+				tu.Equal(t, raw["plain"], any(uint8(3)))
+			}
+		}
+	})
+
+	t.Run("TagOverridesOption", func(t *testing.T) {
+		o := msgpack.EncodeOptions{DurationFormat: msgpack.DurationFormatString}
+		data := marshalBoth(t, o, value)
+		var raw map[string]any
+		tu.NoError(t, msgpack.Unmarshal(data, &raw))
+		tu.Equal(t, raw["raw"], any(uint8(7)))
+		tu.Equal(t, raw["plain"], any("3ns"))
+
+		var v tagged
+		decode(t, msgpack.DecodeOptions{DurationFormat: msgpack.DurationFormatString}, data, &v)
+		tu.Equal(t, reflect.DeepEqual(v, value), true)
+	})
+
+	t.Run("WithTimeTag", func(t *testing.T) {
+		type both struct {
+			At  time.Time     `msgpack:"at,time=unix,duration=s"`
+			For time.Duration `msgpack:"for,duration=s"`
+		}
+		in := both{At: time.Unix(100, 0).UTC(), For: time.Minute}
+		data := marshalBoth(t, msgpack.EncodeOptions{}, in)
+		var raw map[string]any
+		tu.NoError(t, msgpack.Unmarshal(data, &raw))
+		tu.Equal(t, raw["at"], any(uint8(100)))
+		tu.Equal(t, raw["for"], any(uint8(60)))
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		o := msgpack.DecodeOptions{DurationFormat: msgpack.DurationFormatString}
+		var v time.Duration
+		for _, tc := range []struct {
+			in       any
+			expected error
+		}{
+			{in: "soon", expected: def.ErrCanNotDecode},
+			{in: math.NaN(), expected: def.ErrValueOutOfRange},
+			{in: 1e20, expected: def.ErrValueOutOfRange},
+			{in: int64(math.MaxInt64), expected: def.ErrValueOutOfRange},
+		} {
+			data, err := msgpack.Marshal(tc.in)
+			tu.NoError(t, err)
+			tu.IsError(t, o.Unmarshal(data, &v), tc.expected)
+			tu.IsError(t, o.UnmarshalRead(bytes.NewReader(data), &v), tc.expected)
+		}
+	})
+}
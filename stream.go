@@ -0,0 +1,95 @@
+package msgpack
+
+import (
+	"context"
+	"io"
+
+	"github.com/shamaton/msgpack/v3/internal/common"
+	streamdecoding "github.com/shamaton/msgpack/v3/internal/stream/decoding"
+	streamencoding "github.com/shamaton/msgpack/v3/internal/stream/encoding"
+)
+
+// Decoder reads MessagePack-encoded values from a stream.
+type Decoder struct {
+	r   io.Reader
+	opt common.DecodeOption
+}
+
+// NewDecoder returns a Decoder that reads from r.
+// Structs are decoded in the format that StructAsArray sets at this time.
+func NewDecoder(r io.Reader) *Decoder {
+	return DecodeOptions{StructAsArray: StructAsArray}.NewDecoder(r)
+}
+
+// NewDecoder returns a Decoder that reads from r with the options.
+func (o DecodeOptions) NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: r, opt: o.option()}
+}
+
+// Decode reads the next value and stores it into the pointer of v.
+func (d *Decoder) Decode(v interface{}) error {
+	return streamdecoding.DecodeWithOption(d.r, v, d.opt)
+}
+
+// DecodeContext is like Decode but stops once ctx is done. Cancellation
+// is checked between the elements of arrays and maps. If the reader has
+// a SetReadDeadline method, the deadline of ctx is applied to it and a
+// pending read is interrupted on cancellation. In both cases the error
+// of ctx is returned. A ctx with a deadline, or one that is canceled,
+// replaces a read deadline set beforehand, and the read deadline is
+// cleared afterwards. Other contexts leave it as it is.
+func (d *Decoder) DecodeContext(ctx context.Context, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	restore := common.ReadDeadline(ctx, d.r)
+	err := streamdecoding.DecodeContext(ctx, d.r, v, d.opt)
+	restore()
+	if err != nil {
+		return common.DeadlineErr(ctx, err)
+	}
+	return nil
+}
+
+// Encoder writes MessagePack-encoded values to a stream.
+type Encoder struct {
+	w   io.Writer
+	opt common.EncodeOption
+}
+
+// NewEncoder returns an Encoder that writes to w.
+// Structs are encoded in the format that StructAsArray sets at this time.
+func NewEncoder(w io.Writer) *Encoder {
+	return EncodeOptions{StructAsArray: StructAsArray}.NewEncoder(w)
+}
+
+// NewEncoder returns an Encoder that writes to w with the options.
+func (o EncodeOptions) NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{w: w, opt: o.option()}
+}
+
+// Encode writes the MessagePack encoding of v.
+func (e *Encoder) Encode(v interface{}) error {
+	return streamencoding.EncodeWithOption(e.w, v, e.opt)
+}
+
+// EncodeContext is like Encode but stops once ctx is done. Cancellation
+// is checked between the elements of arrays and maps. If the writer has
+// a SetWriteDeadline method, the deadline of ctx is applied to it and a
+// pending write is interrupted on cancellation. In both cases the error
+// of ctx is returned, and part of v may already have been written. A ctx
+// with a deadline, or one that is canceled, replaces a write deadline set
+// beforehand, and the write deadline is cleared afterwards. Other
+// contexts leave it as it is.
+func (e *Encoder) EncodeContext(ctx context.Context, v interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	restore := common.WriteDeadline(ctx, e.w)
+	err := streamencoding.EncodeContext(ctx, e.w, v, e.opt)
+	restore()
+	if err != nil {
+		return common.DeadlineErr(ctx, err)
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/shamaton/msgpack/v3"
+	"github.com/shamaton/msgpack/v3/def"
+	tu "github.com/shamaton/msgpack/v3/internal/common/testutil"
+)
+
+// cancelReader cancels the context after the first read.
+type cancelReader struct {
+	r      io.Reader
+	cancel context.CancelFunc
+}
+
+func (c *cancelReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.cancel()
+	return n, err
+}
+
+// cancelWriter cancels the context after the first write.
+type cancelWriter struct {
+	w      io.Writer
+	cancel context.CancelFunc
+}
+
+func (c *cancelWriter) Write(b []byte) (int, error) {
+	n, err := c.w.Write(b)
+	c.cancel()
+	return n, err
+}
+
+// slowReader waits for delay before each read.
+type slowReader struct {
+	r     io.Reader
+	delay time.Duration
+}
+
+func (s *slowReader) Read(b []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.r.Read(b)
+}
+
+func TestStreamCodec(t *testing.T) {
+	type st struct {
+		A int
+		B []string
+	}
+	in := st{A: 1, B: []string{"a", "b"}}
+
+	for _, asArray := range []bool{false, true} {
+		buf := bytes.Buffer{}
+		enc := msgpack.EncodeOptions{StructAsArray: asArray}.NewEncoder(&buf)
+		tu.NoError(t, enc.Encode(in))
+		tu.NoError(t, enc.EncodeContext(context.Background(), in))
+
+		dec := msgpack.DecodeOptions{StructAsArray: asArray}.NewDecoder(&buf)
+		var out1, out2 st
+		tu.NoError(t, dec.Decode(&out1))
+		tu.NoError(t, dec.DecodeContext(context.Background(), &out2))
+		tu.Equal(t, out1.A, in.A)
+		tu.EqualSlice(t, out1.B, in.B)
+		tu.Equal(t, out2.A, in.A)
+		tu.EqualSlice(t, out2.B, in.B)
+	}
+}
+
+func TestDecodeContext(t *testing.T) {
+	t.Run("Canceled", func(t *testing.T) {
+		b, err := msgpack.Marshal(1)
+		tu.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		var v int
+		err = msgpack.NewDecoder(bytes.NewReader(b)).DecodeContext(ctx, &v)
+		tu.IsError(t, err, context.Canceled)
+	})
+
+	large := make([]int, 100000)
+	for i := range large {
+		large[i] = i
+	}
+	largeMap := make(map[string]int, 10000)
+	for i := 0; i < 10000; i++ {
+		largeMap[string(rune('a'+i%26))+string(rune(i))] = i
+	}
+	testcases := []struct {
+		name string
+		in   any
+		out  any
+	}{
+		{name: "FixedSlice", in: large, out: new([]int)},
+		{name: "Array", in: large, out: new([100000]int)},
+		{name: "Interface", in: large, out: new(any)},
+		{name: "Map", in: largeMap, out: new(map[string]int)},
+		{name: "InterfaceMap", in: largeMap, out: new(any)},
+	}
+	for _, tc := range testcases {
+		t.Run("CanceledBetweenElements"+tc.name, func(t *testing.T) {
+			b, err := msgpack.Marshal(tc.in)
+			tu.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			r := &cancelReader{r: bytes.NewReader(b), cancel: cancel}
+			err = msgpack.NewDecoder(r).DecodeContext(ctx, tc.out)
+			tu.IsError(t, err, context.Canceled)
+		})
+	}
+
+	t.Run("ReadDeadline", func(t *testing.T) {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		var v int
+		err := msgpack.NewDecoder(c1).DecodeContext(ctx, &v)
+		tu.IsError(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("CancelPendingRead", func(t *testing.T) {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		dec := msgpack.NewDecoder(c1)
+		errCh := make(chan error, 1)
+		go func() {
+			var v int
+			errCh <- dec.DecodeContext(ctx, &v)
+		}()
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+		tu.IsError(t, <-errCh, context.Canceled)
+
+		// the deadline is cleared afterwards
+		go func() {
+			b, _ := msgpack.Marshal(7)
+			_, _ = c2.Write(b)
+		}()
+		var v int
+		tu.NoError(t, dec.DecodeContext(context.Background(), &v))
+		tu.Equal(t, v, 7)
+	})
+
+	t.Run("PresetDeadline", func(t *testing.T) {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+
+		// contexts without a deadline keep the one set beforehand
+		tu.NoError(t, c1.SetReadDeadline(time.Now().Add(-time.Second)))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var v int
+		dec := msgpack.NewDecoder(c1)
+		tu.IsError(t, dec.DecodeContext(context.Background(), &v), os.ErrDeadlineExceeded)
+		tu.IsError(t, dec.DecodeContext(ctx, &v), os.ErrDeadlineExceeded)
+	})
+
+	t.Run("ErrorAfterDeadline", func(t *testing.T) {
+		// errors other than timeouts are kept once the deadline passes
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		r := &slowReader{r: bytes.NewReader([]byte{def.Nil + 1}), delay: 30 * time.Millisecond}
+		var v int
+		err := msgpack.NewDecoder(r).DecodeContext(ctx, &v)
+		tu.IsError(t, err, def.ErrCanNotDecode)
+	})
+}
+
+func TestEncodeContext(t *testing.T) {
+	t.Run("Canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		buf := bytes.Buffer{}
+		err := msgpack.NewEncoder(&buf).EncodeContext(ctx, 1)
+		tu.IsError(t, err, context.Canceled)
+		tu.Equal(t, buf.Len(), 0)
+	})
+
+	large := make([]int, 100000)
+	largeMap := make(map[int]string, 10000)
+	for i := 0; i < 10000; i++ {
+		largeMap[i] = "value"
+	}
+	testcases := []struct {
+		name string
+		o    msgpack.EncodeOptions
+		in   any
+	}{
+		{name: "FixedSlice", in: large},
+		{name: "Array", in: [100000]int{}},
+		{name: "Slice", in: make([][]int, 100000)},
+		{name: "Map", in: largeMap},
+		{name: "CanonicalMap", o: msgpack.EncodeOptions{Canonical: true}, in: largeMap},
+	}
+	for _, tc := range testcases {
+		t.Run("CanceledBetweenElements"+tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			w := &cancelWriter{w: io.Discard, cancel: cancel}
+			err := tc.o.NewEncoder(w).EncodeContext(ctx, tc.in)
+			tu.IsError(t, err, context.Canceled)
+		})
+	}
+
+	t.Run("WriteDeadline", func(t *testing.T) {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		err := msgpack.NewEncoder(c1).EncodeContext(ctx, 1)
+		tu.IsError(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("PresetDeadline", func(t *testing.T) {
+		c1, c2 := net.Pipe()
+		defer c1.Close()
+		defer c2.Close()
+
+		// contexts without a deadline keep the one set beforehand
+		tu.NoError(t, c1.SetWriteDeadline(time.Now().Add(-time.Second)))
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		enc := msgpack.NewEncoder(c1)
+		tu.IsError(t, enc.EncodeContext(context.Background(), 1), os.ErrDeadlineExceeded)
+		tu.IsError(t, enc.EncodeContext(ctx, 1), os.ErrDeadlineExceeded)
+	})
+
+	t.Run("NotCanceled", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		err := msgpack.NewEncoder(&buf).EncodeContext(context.Background(), large)
+		tu.NoError(t, err)
+		b, err := msgpack.Marshal(large)
+		tu.NoError(t, err)
+		tu.EqualSlice(t, buf.Bytes(), b)
+	})
+}
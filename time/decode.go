@@ -53,6 +53,9 @@ func (td *timeDecoder) readSize8Safe(index int, d *[]byte) ([]byte, int, bool) {
 }
 
 func (td *timeDecoder) IsType(offset int, d *[]byte) bool {
+	if td.isZone(offset, d) {
+		return true
+	}
 	code, offset, ok := td.readSize1Safe(offset, d)
 	if !ok {
 		return false
@@ -93,6 +96,9 @@ func (td *timeDecoder) IsType(offset int, d *[]byte) bool {
 }
 
 func (td *timeDecoder) AsValue(offset int, k reflect.Kind, d *[]byte) (interface{}, int, error) {
+	if td.isZone(offset, d) {
+		return td.asZone(offset, d)
+	}
 	code, offset, ok := td.readSize1Safe(offset, d)
 	if !ok {
 		return zero, 0, def.ErrTooShortBytes
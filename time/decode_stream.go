@@ -25,6 +25,9 @@ func (td *timeStreamDecoder) IsType(code byte, innerType int8, dataLength int) b
 	case def.Fixext4, def.Fixext8:
 		return innerType == td.Code()
 	case def.Ext8:
+		if decodeZone && innerType == zoneCode && dataLength >= zoneHeaderLen {
+			return true
+		}
 		return innerType == td.Code() && dataLength == 12
 	}
 	return false
@@ -58,6 +61,10 @@ func (td *timeStreamDecoder) ToValue(code byte, data []byte, k reflect.Kind) (in
 		return v.UTC(), nil
 
 	case def.Ext8:
+		// zoned timestamps are longer than timestamp 96
+		if decodeZone && len(data) >= zoneHeaderLen {
+			return timeFromZoneData(data)
+		}
 		if len(data) < def.Byte4+def.Byte8 {
 			return zero, def.ErrTooShortBytes
 		}
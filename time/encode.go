@@ -26,16 +26,18 @@ func (s *timeEncoder) Type() reflect.Type {
 
 func (s *timeEncoder) CalcByteSize(value reflect.Value) (int, error) {
 	t := value.Interface().(time.Time)
-	sec := t.Unix()
-	if sec >= 0 {
-		secs := uint64(sec) // #nosec G115 -- non-negative Unix seconds are checked before timestamp64 packing.
-		if secs>>34 == 0 {
-			data := uint64(t.Nanosecond())<<34 | secs // #nosec G115 -- time.Nanosecond is always in [0, 999999999].
-			if data&0xffffffff00000000 == 0 {
-				return def.Byte1 + def.Byte1 + def.Byte4, nil
-			}
-			return def.Byte1 + def.Byte1 + def.Byte8, nil
-		}
+	if encodeZone {
+		return s.calcZoneByteSize(t)
+	}
+	w, _, err := timestampWidth(t)
+	if err != nil {
+		return 0, err
+	}
+	switch w {
+	case Width32:
+		return def.Byte1 + def.Byte1 + def.Byte4, nil
+	case Width64:
+		return def.Byte1 + def.Byte1 + def.Byte8, nil
 	}
 
 	return def.Byte1 + def.Byte1 + def.Byte1 + def.Byte4 + def.Byte8, nil
@@ -43,30 +45,30 @@ func (s *timeEncoder) CalcByteSize(value reflect.Value) (int, error) {
 
 func (s *timeEncoder) WriteToBytes(value reflect.Value, offset int, bytes *[]byte) int {
 	t := value.Interface().(time.Time)
+	if encodeZone {
+		return s.writeZone(t, offset, bytes)
+	}
 
-	sec := t.Unix()
-	if sec >= 0 {
-		secs := uint64(sec) // #nosec G115 -- non-negative Unix seconds are checked before timestamp64 packing.
-		if secs>>34 == 0 {
-			data := uint64(t.Nanosecond())<<34 | secs // #nosec G115 -- time.Nanosecond is always in [0, 999999999].
-			if data&0xffffffff00000000 == 0 {
-				offset = s.SetByte1Int(def.Fixext4, offset, bytes)
-				offset = s.SetByte1Int(def.TimeStamp, offset, bytes)
-				offset = s.SetByte4Uint64(data, offset, bytes)
-				return offset
-			}
+	// CalcByteSize has reported times that the width can't represent
+	w, data, _ := timestampWidth(t)
+	switch w {
+	case Width32:
+		offset = s.SetByte1Int(def.Fixext4, offset, bytes)
+		offset = s.SetByte1Int(def.TimeStamp, offset, bytes)
+		offset = s.SetByte4Uint64(data, offset, bytes)
+		return offset
 
-			offset = s.SetByte1Int(def.Fixext8, offset, bytes)
-			offset = s.SetByte1Int(def.TimeStamp, offset, bytes)
-			offset = s.SetByte8Uint64(data, offset, bytes)
-			return offset
-		}
+	case Width64:
+		offset = s.SetByte1Int(def.Fixext8, offset, bytes)
+		offset = s.SetByte1Int(def.TimeStamp, offset, bytes)
+		offset = s.SetByte8Uint64(data, offset, bytes)
+		return offset
 	}
 
 	offset = s.SetByte1Int(def.Ext8, offset, bytes)
 	offset = s.SetByte1Int(12, offset, bytes)
 	offset = s.SetByte1Int(def.TimeStamp, offset, bytes)
 	offset = s.SetByte4Int(t.Nanosecond(), offset, bytes)
-	offset = s.SetByte8Int64(sec, offset, bytes)
+	offset = s.SetByte8Int64(t.Unix(), offset, bytes)
 	return offset
 }
@@ -24,36 +24,38 @@ func (timeStreamEncoder) Type() reflect.Type {
 
 func (e timeStreamEncoder) Write(w ext.StreamWriter, value reflect.Value) error {
 	t := value.Interface().(time.Time)
+	if encodeZone {
+		return e.writeZone(w, t)
+	}
 
-	sec := t.Unix()
-	if sec >= 0 {
-		secs := uint64(sec) // #nosec G115 -- non-negative Unix seconds are checked before timestamp64 packing.
-		if secs>>34 == 0 {
-			data := uint64(t.Nanosecond())<<34 | secs // #nosec G115 -- time.Nanosecond is always in [0, 999999999].
-			if data&0xffffffff00000000 == 0 {
-				if err := w.WriteByte1Int(def.Fixext4); err != nil {
-					return err
-				}
-				if err := w.WriteByte1Int(def.TimeStamp); err != nil {
-					return err
-				}
-				if err := w.WriteByte4Uint64(data); err != nil {
-					return err
-				}
-				return nil
-			}
+	width, data, err := timestampWidth(t)
+	if err != nil {
+		return err
+	}
+	switch width {
+	case Width32:
+		if err := w.WriteByte1Int(def.Fixext4); err != nil {
+			return err
+		}
+		if err := w.WriteByte1Int(def.TimeStamp); err != nil {
+			return err
+		}
+		if err := w.WriteByte4Uint64(data); err != nil {
+			return err
+		}
+		return nil
 
-			if err := w.WriteByte1Int(def.Fixext8); err != nil {
-				return err
-			}
-			if err := w.WriteByte1Int(def.TimeStamp); err != nil {
-				return err
-			}
-			if err := w.WriteByte8Uint64(data); err != nil {
-				return err
-			}
-			return nil
+	case Width64:
+		if err := w.WriteByte1Int(def.Fixext8); err != nil {
+			return err
+		}
+		if err := w.WriteByte1Int(def.TimeStamp); err != nil {
+			return err
+		}
+		if err := w.WriteByte8Uint64(data); err != nil {
+			return err
 		}
+		return nil
 	}
 
 	if err := w.WriteByte1Int(def.Ext8); err != nil {
@@ -68,7 +70,7 @@ func (e timeStreamEncoder) Write(w ext.StreamWriter, value reflect.Value) error
 	if err := w.WriteByte4Int(t.Nanosecond()); err != nil {
 		return err
 	}
-	if err := w.WriteByte8Int64(sec); err != nil {
+	if err := w.WriteByte8Int64(t.Unix()); err != nil {
 		return err
 	}
 	return nil
@@ -6,3 +6,8 @@ var decodeAsLocal = false
 func SetDecodedAsLocal(b bool) {
 	decodeAsLocal = b
 }
+
+// DecodedAsLocal reports whether decoded times are set to local time.
+func DecodedAsLocal() bool {
+	return decodeAsLocal
+}
@@ -0,0 +1,63 @@
+package time
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shamaton/msgpack/v3/def"
+)
+
+// Width is the size in bits of the seconds and nanoseconds of a timestamp.
+type Width int
+
+const (
+	// WidthAuto selects the smallest timestamp that holds the value,
+	// which is the default.
+	WidthAuto Width = 0
+	// Width32 is timestamp32, which holds seconds from 1970 to 2106
+	// without nanoseconds.
+	Width32 Width = 32
+	// Width64 is timestamp64, which holds seconds from 1970 to 2514
+	// with nanoseconds.
+	Width64 Width = 64
+	// Width96 is timestamp96, which holds any time.Time.
+	Width96 Width = 96
+)
+
+var encodeWidth = WidthAuto
+
+// SetEncodedWidth sets the timestamp width that times are encoded with.
+// Times that the width can't represent fail to encode. It does not apply
+// while SetEncodedWithZone is enabled.
+func SetEncodedWidth(w Width) {
+	encodeWidth = w
+}
+
+// timestampWidth returns the width that t is encoded with and the packed
+// seconds and nanoseconds of timestamp32 and timestamp64.
+func timestampWidth(t time.Time) (Width, uint64, error) {
+	var data uint64
+	w := Width96
+	sec := t.Unix()
+	if sec >= 0 {
+		secs := uint64(sec) // #nosec G115 -- non-negative Unix seconds are checked before timestamp64 packing.
+		if secs>>34 == 0 {
+			data = uint64(t.Nanosecond())<<34 | secs // #nosec G115 -- time.Nanosecond is always in [0, 999999999].
+			w = Width64
+			if data&0xffffffff00000000 == 0 {
+				w = Width32
+			}
+		}
+	}
+
+	switch encodeWidth {
+	case WidthAuto:
+		return w, data, nil
+	case Width32, Width64, Width96:
+		if w > encodeWidth {
+			return 0, 0, fmt.Errorf("%w %v for timestamp%d", def.ErrValueOutOfRange, t, encodeWidth)
+		}
+		return encodeWidth, data, nil
+	}
+	return 0, 0, fmt.Errorf("%w timestamp%d", def.ErrUnsupportedLength, encodeWidth)
+}
@@ -0,0 +1,99 @@
+package time
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/ext"
+	"github.com/shamaton/msgpack/v3/internal/common"
+	tu "github.com/shamaton/msgpack/v3/internal/common/testutil"
+)
+
+func encodeBoth(t *testing.T, value reflect.Value) ([]byte, error) {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	buffer := common.GetBuffer()
+	defer common.PutBuffer(buffer)
+	streamErr := StreamEncoder.Write(ext.CreateStreamWriter(buf, buffer), value)
+
+	size, err := Encoder.CalcByteSize(value)
+	if err != nil || streamErr != nil {
+		return nil, errors.Join(err, streamErr)
+	}
+	tu.NoError(t, buffer.Flush(buf))
+
+	data := make([]byte, size)
+	tu.Equal(t, Encoder.WriteToBytes(value, 0, &data), size)
+	tu.EqualSlice(t, buf.Bytes(), data)
+	return data, nil
+}
+
+func TestWidth(t *testing.T) {
+	defer SetEncodedWidth(WidthAuto)
+
+	small := time.Unix(1000, 0).UTC()
+	nanos := time.Unix(1000, 500).UTC()
+	late := time.Unix(1<<33, 500).UTC()
+	before := time.Unix(-1, 0).UTC()
+
+	tests := []struct {
+		name  string
+		width Width
+		time  time.Time
+		code  byte
+	}{
+		{name: "Auto32", width: WidthAuto, time: small, code: def.Fixext4},
+		{name: "Auto64", width: WidthAuto, time: nanos, code: def.Fixext8},
+		{name: "Auto96", width: WidthAuto, time: before, code: def.Ext8},
+		{name: "32", width: Width32, time: small, code: def.Fixext4},
+		{name: "64Small", width: Width64, time: small, code: def.Fixext8},
+		{name: "64Nanos", width: Width64, time: nanos, code: def.Fixext8},
+		{name: "64Late", width: Width64, time: late, code: def.Fixext8},
+		{name: "96Small", width: Width96, time: small, code: def.Ext8},
+		{name: "96Before", width: Width96, time: before, code: def.Ext8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SetEncodedWidth(tt.width)
+			data, err := encodeBoth(t, reflect.ValueOf(tt.time))
+			tu.NoError(t, err)
+			tu.Equal(t, data[0], tt.code)
+
+			v, _, err := Decoder.AsValue(0, reflect.Struct, &data)
+			tu.NoError(t, err)
+			tu.Equal(t, v.(time.Time).Equal(tt.time), true)
+		})
+	}
+
+	errcases := []struct {
+		name     string
+		width    Width
+		time     time.Time
+		expected error
+	}{
+		{name: "32Nanos", width: Width32, time: nanos, expected: def.ErrValueOutOfRange},
+		{name: "32Late", width: Width32, time: time.Unix(1<<32, 0), expected: def.ErrValueOutOfRange},
+		{name: "32Before", width: Width32, time: before, expected: def.ErrValueOutOfRange},
+		{name: "64TooLate", width: Width64, time: time.Unix(1<<34, 0), expected: def.ErrValueOutOfRange},
+		{name: "64Before", width: Width64, time: before, expected: def.ErrValueOutOfRange},
+		{name: "Unknown", width: 48, time: small, expected: def.ErrUnsupportedLength},
+	}
+	for _, tt := range errcases {
+		t.Run(tt.name, func(t *testing.T) {
+			SetEncodedWidth(tt.width)
+			_, err := encodeBoth(t, reflect.ValueOf(tt.time))
+			var joined interface{ Unwrap() []error }
+			tu.Equal(t, errors.As(err, &joined), true)
+			errs := joined.Unwrap()
+			tu.Equal(t, len(errs), 2)
+			for _, err := range errs {
+				tu.IsError(t, err, tt.expected)
+			}
+		})
+	}
+}
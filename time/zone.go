@@ -0,0 +1,216 @@
+package time
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/ext"
+)
+
+var (
+	encodeZone = false
+	decodeZone = false
+	zoneCode   = int8(0x7f)
+)
+
+// SetEncodedWithZone sets time.Time values to be encoded with their
+// location. The ext type is set by SetZoneCode and laid out as ext8:
+//
+//	+--------+--------+--------+-------------+-------------+--------------+-----------+
+//	|  0xc7  | length |  code  | nsec uint32 |  sec int64  | offset int32 | zone name |
+//	+--------+--------+--------+-------------+-------------+--------------+-----------+
+//
+// offset is the UTC offset in seconds at that instant. The zone name is
+// the IANA name of the location, such as "Asia/Tokyo", or the zone
+// abbreviation for time.Local and fixed zones.
+func SetEncodedWithZone(b bool) {
+	encodeZone = b
+}
+
+// SetDecodedWithZone sets values of the ext type of SetEncodedWithZone to
+// be decoded as time.Time besides standard timestamps. A decoded time uses
+// the named location when it is known and has the same offset, and a
+// fixed zone otherwise, regardless of SetDecodedAsLocal.
+func SetDecodedWithZone(b bool) {
+	decodeZone = b
+}
+
+// SetZoneCode sets the ext type used by SetEncodedWithZone and
+// SetDecodedWithZone. It is an application-defined type, so set it if the
+// default of 127 is taken.
+func SetZoneCode(code int8) {
+	zoneCode = code
+}
+
+// ZoneCode returns the ext type of zoned times and whether they are
+// encoded or decoded, in which case no other coder may use the type.
+func ZoneCode() (int8, bool) {
+	return zoneCode, encodeZone || decodeZone
+}
+
+const (
+	zoneHeaderLen  = def.Byte4 + def.Byte8 + def.Byte4
+	zoneMaxNameLen = 255 - zoneHeaderLen
+)
+
+// zoneData returns the ext data of t.
+func zoneData(t time.Time) ([]byte, error) {
+	name := t.Location().String()
+	abbr, offset := t.Zone()
+	if t.Location() == time.Local {
+		name = abbr
+	}
+	if len(name) > zoneMaxNameLen {
+		return nil, fmt.Errorf("%w: zone name %q is longer than %d bytes", def.ErrUnsupportedLength, name, zoneMaxNameLen)
+	}
+
+	data := make([]byte, zoneHeaderLen+len(name))
+	binary.BigEndian.PutUint32(data, uint32(t.Nanosecond()))               // #nosec G115 -- time.Nanosecond is always in [0, 999999999].
+	binary.BigEndian.PutUint64(data[def.Byte4:], uint64(t.Unix()))         // #nosec G115 -- seconds are encoded as signed two's-complement bytes.
+	binary.BigEndian.PutUint32(data[def.Byte4+def.Byte8:], uint32(offset)) // #nosec G115 -- offsets are encoded as signed two's-complement bytes.
+	copy(data[zoneHeaderLen:], name)
+	return data, nil
+}
+
+// timeFromZoneData returns the time held by the ext data.
+func timeFromZoneData(data []byte) (time.Time, error) {
+	if len(data) < zoneHeaderLen {
+		return zero, def.ErrTooShortBytes
+	}
+	nano := binary.BigEndian.Uint32(data)
+	if nano > 999999999 {
+		return zero, fmt.Errorf("in zoned timestamps, nanoseconds must not be larger than 999999999 : %d", nano)
+	}
+	sec := int64(binary.BigEndian.Uint64(data[def.Byte4:]))                   // #nosec G115 -- seconds are encoded as signed two's-complement bytes.
+	offset := int(int32(binary.BigEndian.Uint32(data[def.Byte4+def.Byte8:]))) // #nosec G115 -- offsets are encoded as signed two's-complement bytes.
+	name := string(data[zoneHeaderLen:])
+
+	v := time.Unix(sec, int64(nano))
+	if loc := loadLocation(name); loc != nil {
+		if lv := v.In(loc); zoneOffset(lv) == offset {
+			return lv, nil
+		}
+	}
+	return v.In(time.FixedZone(name, offset)), nil
+}
+
+func zoneOffset(t time.Time) int {
+	_, offset := t.Zone()
+	return offset
+}
+
+// locations caches the results of time.LoadLocation. Zone names come from
+// the decoded data, so misses are only cached up to maxZoneMisses.
+var (
+	locations  sync.Map
+	zoneMisses atomic.Int32
+)
+
+const maxZoneMisses = 64
+
+func loadLocation(name string) *time.Location {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+	if !validZoneName(name) {
+		return nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		if zoneMisses.Load() >= maxZoneMisses {
+			return nil
+		}
+		zoneMisses.Add(1)
+		loc = nil
+	}
+	locations.Store(name, loc)
+	return loc
+}
+
+// validZoneName reports whether name is shaped like an IANA zone name:
+// "/"-separated elements that start with a letter and hold only letters,
+// digits, "_", "-" and "+".
+func validZoneName(name string) bool {
+	if name == "" {
+		return false
+	}
+	start := true
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z':
+		case start:
+			return false
+		case c == '/':
+			start = true
+			continue
+		case '0' <= c && c <= '9', c == '_', c == '-', c == '+':
+		default:
+			return false
+		}
+		start = false
+	}
+	return !start
+}
+
+func (s *timeEncoder) calcZoneByteSize(t time.Time) (int, error) {
+	data, err := zoneData(t)
+	if err != nil {
+		return 0, err
+	}
+	return def.Byte1 + def.Byte1 + def.Byte1 + len(data), nil
+}
+
+func (s *timeEncoder) writeZone(t time.Time, offset int, bytes *[]byte) int {
+	// the name length was checked by calcZoneByteSize
+	data, _ := zoneData(t)
+	offset = s.SetByte1Int(def.Ext8, offset, bytes)
+	offset = s.SetByte1Int(len(data), offset, bytes)
+	offset = s.SetByte1Int64(int64(zoneCode), offset, bytes)
+	return s.SetBytes(data, offset, bytes)
+}
+
+func (e timeStreamEncoder) writeZone(w ext.StreamWriter, t time.Time) error {
+	data, err := zoneData(t)
+	if err != nil {
+		return err
+	}
+	if err := w.WriteByte1Int(def.Ext8); err != nil {
+		return err
+	}
+	if err := w.WriteByte1Int(len(data)); err != nil {
+		return err
+	}
+	if err := w.WriteByte1Int64(int64(zoneCode)); err != nil {
+		return err
+	}
+	return w.WriteBytes(data)
+}
+
+// isZone reports whether a zoned timestamp is at offset.
+func (td *timeDecoder) isZone(offset int, d *[]byte) bool {
+	if !decodeZone || len(*d) < offset+def.Byte1+def.Byte1+def.Byte1 {
+		return false
+	}
+	code, offset := td.ReadSize1(offset, d)
+	l, offset := td.ReadSize1(offset, d)
+	t, offset := td.ReadSize1(offset, d)
+	return code == def.Ext8 && timeCodeFromByte(t) == zoneCode &&
+		int(l) >= zoneHeaderLen && len(*d) >= offset+int(l)
+}
+
+func (td *timeDecoder) asZone(offset int, d *[]byte) (time.Time, int, error) {
+	_, offset = td.ReadSize1(offset, d)
+	l, offset := td.ReadSize1(offset, d)
+	_, offset = td.ReadSize1(offset, d)
+	data, offset := td.ReadSizeN(offset, int(l), d)
+	v, err := timeFromZoneData(data)
+	if err != nil {
+		return zero, 0, err
+	}
+	return v, offset, nil
+}
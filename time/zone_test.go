@@ -0,0 +1,172 @@
+package time
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/ext"
+	"github.com/shamaton/msgpack/v3/internal/common"
+	tu "github.com/shamaton/msgpack/v3/internal/common/testutil"
+)
+
+func TestZoneRoundTrip(t *testing.T) {
+	SetEncodedWithZone(true)
+	defer SetEncodedWithZone(false)
+	SetDecodedWithZone(true)
+	defer SetDecodedWithZone(false)
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	tu.NoError(t, err)
+	newYork, err := time.LoadLocation("America/New_York")
+	tu.NoError(t, err)
+
+	tests := []struct {
+		name string
+		time time.Time
+	}{
+		{name: "UTC", time: time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)},
+		{name: "IANA", time: time.Date(2024, 1, 2, 3, 4, 5, 6, tokyo)},
+		{name: "IANADaylightSaving", time: time.Date(2024, 7, 1, 12, 0, 0, 0, newYork)},
+		{name: "FixedZone", time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("XYZ", -3*60*60-30*60))},
+		{name: "UnnamedFixedZone", time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone("", 5*60*60))},
+		{name: "BeforeEpoch", time: time.Date(1900, 1, 2, 3, 4, 5, 0, newYork)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value := reflect.ValueOf(tt.time)
+			size, err := Encoder.CalcByteSize(value)
+			tu.NoError(t, err)
+			data := make([]byte, size)
+			tu.Equal(t, Encoder.WriteToBytes(value, 0, &data), size)
+
+			tu.Equal(t, Decoder.IsType(0, &data), true)
+			v, offset, err := Decoder.AsValue(0, reflect.Struct, &data)
+			tu.NoError(t, err)
+			tu.Equal(t, offset, size)
+			assertSameZone(t, v.(time.Time), tt.time)
+
+			buf := &bytes.Buffer{}
+			buffer := common.GetBuffer()
+			defer common.PutBuffer(buffer)
+			err = StreamEncoder.Write(ext.CreateStreamWriter(buf, buffer), value)
+			tu.NoError(t, err)
+			tu.NoError(t, buffer.Flush(buf))
+			tu.EqualSlice(t, buf.Bytes(), data)
+
+			tu.Equal(t, StreamDecoder.IsType(data[0], int8(data[2]), len(data[3:])), true)
+			v, err = StreamDecoder.ToValue(data[0], data[3:], reflect.Struct)
+			tu.NoError(t, err)
+			assertSameZone(t, v.(time.Time), tt.time)
+		})
+	}
+
+	t.Run("Local", func(t *testing.T) {
+		in := time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local)
+		data, err := zoneData(in)
+		tu.NoError(t, err)
+		v, err := timeFromZoneData(data)
+		tu.NoError(t, err)
+		tu.Equal(t, v.Equal(in), true)
+		tu.Equal(t, zoneOffset(v), zoneOffset(in))
+	})
+}
+
+func assertSameZone(t *testing.T, actual, expected time.Time) {
+	t.Helper()
+	tu.Equal(t, actual.Equal(expected), true)
+	tu.Equal(t, actual.Location().String(), expected.Location().String())
+	aName, aOffset := actual.Zone()
+	eName, eOffset := expected.Zone()
+	tu.Equal(t, aName, eName)
+	tu.Equal(t, aOffset, eOffset)
+}
+
+func TestZoneErrors(t *testing.T) {
+	SetEncodedWithZone(true)
+	defer SetEncodedWithZone(false)
+	SetDecodedWithZone(true)
+	defer SetDecodedWithZone(false)
+
+	t.Run("LongName", func(t *testing.T) {
+		in := time.Date(2024, 1, 2, 3, 4, 5, 0, time.FixedZone(strings.Repeat("a", 240), 0))
+		_, err := Encoder.CalcByteSize(reflect.ValueOf(in))
+		tu.IsError(t, err, def.ErrUnsupportedLength)
+	})
+
+	t.Run("InvalidNanoseconds", func(t *testing.T) {
+		data := make([]byte, zoneHeaderLen)
+		data[0] = 0xff
+		_, err := StreamDecoder.ToValue(def.Ext8, data, reflect.Struct)
+		tu.ErrorContains(t, err, "nanoseconds")
+	})
+
+	t.Run("NotZoned", func(t *testing.T) {
+		data := []byte{def.Fixext4, byte(zoneCode), 0, 0, 0, 0}
+		tu.Equal(t, Decoder.IsType(0, &data), false)
+		tu.Equal(t, StreamDecoder.IsType(def.Ext8, zoneCode, 12), false)
+
+		data = []byte{def.Ext8, zoneHeaderLen, byte(zoneCode), 0}
+		tu.Equal(t, Decoder.IsType(0, &data), false)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		data, err := zoneData(time.Unix(0, 0).UTC())
+		tu.NoError(t, err)
+		data = append([]byte{def.Ext8, byte(len(data)), byte(zoneCode)}, data...)
+
+		// decoding does not depend on encoding
+		SetEncodedWithZone(false)
+		defer SetEncodedWithZone(true)
+		tu.Equal(t, Decoder.IsType(0, &data), true)
+		tu.Equal(t, StreamDecoder.IsType(data[0], zoneCode, len(data[3:])), true)
+
+		SetDecodedWithZone(false)
+		defer SetDecodedWithZone(true)
+		tu.Equal(t, Decoder.IsType(0, &data), false)
+		tu.Equal(t, StreamDecoder.IsType(data[0], zoneCode, len(data[3:])), false)
+	})
+}
+
+func TestLoadLocation(t *testing.T) {
+	t.Run("ZoneNames", func(t *testing.T) {
+		valid := []string{"UTC", "Local", "Asia/Tokyo", "America/Port-au-Prince", "Etc/GMT+5", "EST5EDT", "America/Argentina/Buenos_Aires"}
+		for _, name := range valid {
+			tu.Equal(t, validZoneName(name), true)
+		}
+		invalid := []string{"", "/etc/passwd", "../Asia/Tokyo", "Asia/../Tokyo", "Asia/", "Asia//Tokyo", "Asia\\Tokyo", "+0900", "Asia/Tokyo\x00", "Asia/Tōkyō"}
+		for _, name := range invalid {
+			tu.Equal(t, validZoneName(name), false)
+			tu.Equal(t, loadLocation(name) == nil, true)
+		}
+	})
+
+	t.Run("Found", func(t *testing.T) {
+		loc := loadLocation("Asia/Tokyo")
+		tu.Equal(t, loc != nil, true)
+		tu.Equal(t, loc.String(), "Asia/Tokyo")
+		tu.Equal(t, loadLocation("Asia/Tokyo") == loc, true)
+	})
+
+	t.Run("BoundedMisses", func(t *testing.T) {
+		for i := 0; i < maxZoneMisses*2; i++ {
+			name := fmt.Sprintf("Unknown/Zone%d", i)
+			tu.Equal(t, loadLocation(name) == nil, true)
+		}
+		tu.Equal(t, zoneMisses.Load(), int32(maxZoneMisses))
+
+		n := 0
+		locations.Range(func(_, loc any) bool {
+			if loc.(*time.Location) == nil {
+				n++
+			}
+			return true
+		})
+		tu.Equal(t, n <= maxZoneMisses, true)
+	})
+}
@@ -0,0 +1,193 @@
+package msgpack
+
+import (
+	"errors"
+	"io"
+	"iter"
+
+	"github.com/shamaton/msgpack/v3/def"
+	"github.com/shamaton/msgpack/v3/internal/common"
+	"github.com/shamaton/msgpack/v3/internal/decoding"
+	streamdecoding "github.com/shamaton/msgpack/v3/internal/stream/decoding"
+)
+
+// UnmarshalAs analyzes the MessagePack-encoded data and returns
+// the result as T. Structs are decoded in the format that StructAsArray
+// sets, and their fields are looked up by type as for Unmarshal, since Go
+// has no variables per type parameter to resolve them once for T.
+// UnmarshalAsWith takes DecodeOptions instead.
+func UnmarshalAs[T any](data []byte) (T, error) {
+	var v T
+	err := decoding.Decode(data, &v, StructAsArray)
+	return v, err
+}
+
+// UnmarshalAsWith analyzes the MessagePack-encoded data according to o
+// and returns the result as T.
+func UnmarshalAsWith[T any](o DecodeOptions, data []byte) (T, error) {
+	var v T
+	err := decoding.DecodeWithOption(data, &v, o.option())
+	return v, err
+}
+
+// ReadAs reads the MessagePack-encoded data from reader and returns
+// the result as T. Structs are decoded as for UnmarshalAs, and ReadAsWith
+// takes DecodeOptions instead.
+func ReadAs[T any](r io.Reader) (T, error) {
+	var v T
+	err := streamdecoding.Decode(r, &v, StructAsArray)
+	return v, err
+}
+
+// ReadAsWith reads the MessagePack-encoded data from reader according to
+// o and returns the result as T.
+func ReadAsWith[T any](o DecodeOptions, r io.Reader) (T, error) {
+	var v T
+	err := streamdecoding.DecodeWithOption(r, &v, o.option())
+	return v, err
+}
+
+// TypedDecoder reads consecutive values of T from a stream.
+type TypedDecoder[T any] struct {
+	r   *countReader
+	opt common.DecodeOption
+}
+
+// NewTypedDecoder returns a TypedDecoder that reads from r.
+// Structs are decoded in the format that StructAsArray sets at this time,
+// and NewTypedDecoderWith takes DecodeOptions instead.
+func NewTypedDecoder[T any](r io.Reader) *TypedDecoder[T] {
+	return NewTypedDecoderWith[T](DecodeOptions{StructAsArray: StructAsArray}, r)
+}
+
+// NewTypedDecoderWith returns a TypedDecoder that reads from r according
+// to o. The limits of o apply to each value.
+func NewTypedDecoderWith[T any](o DecodeOptions, r io.Reader) *TypedDecoder[T] {
+	return &TypedDecoder[T]{r: &countReader{r: r}, opt: o.option()}
+}
+
+// Decode reads the next value. It returns io.EOF when the stream ends
+// between values and io.ErrUnexpectedEOF when it ends inside a value.
+func (d *TypedDecoder[T]) Decode() (T, error) {
+	var v T
+	if d.r.r == nil {
+		return v, def.ErrNoData
+	}
+
+	n := d.r.n
+	err := streamdecoding.DecodeWithOption(d.r, &v, d.opt)
+	if errors.Is(err, io.EOF) {
+		if d.r.n == n {
+			return v, io.EOF
+		}
+		return v, io.ErrUnexpectedEOF
+	}
+	return v, err
+}
+
+// All returns an iterator over the remaining values in the stream.
+// The iteration stops after the end of the stream or the first error,
+// which is yielded with the zero value of T.
+func (d *TypedDecoder[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			v, err := d.Decode()
+			if err == io.EOF {
+				return
+			}
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// countReader counts the bytes read from r.
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(b []byte) (int, error) {
+	n, err := c.r.Read(b)
+	c.n += int64(n)
+	return n, err
+}
+
+// ReadArray returns an iterator over the elements of an array read from r.
+// Each element is decoded into a reused T that is reset to its zero value
+// first. The iteration stops at the first error, which is yielded with
+// the zero value of T.
+func ReadArray[T any](r io.Reader) iter.Seq2[T, error] {
+	asArray := StructAsArray
+	return func(yield func(T, error) bool) {
+		var v, zero T
+		s := streamdecoding.NewSequence(r, asArray)
+		defer s.Close()
+
+		l, err := s.ArrayLength()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		for i := 0; i < l; i++ {
+			v = zero
+			if err := s.Next(&v); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+// MapReader reads the key-value pairs of a map from a stream.
+type MapReader[K, V any] struct {
+	r       io.Reader
+	asArray bool
+	err     error
+}
+
+// NewMapReader returns a MapReader that reads from r.
+func NewMapReader[K, V any](r io.Reader) *MapReader[K, V] {
+	return &MapReader[K, V]{r: r, asArray: StructAsArray}
+}
+
+// All returns an iterator over the key-value pairs of a map read from
+// the stream. The iteration stops at the first error, which is reported
+// by Err.
+func (m *MapReader[K, V]) All() iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		var k, zeroK K
+		var v, zeroV V
+		s := streamdecoding.NewSequence(m.r, m.asArray)
+		defer s.Close()
+
+		l, err := s.MapLength()
+		if err != nil {
+			m.err = err
+			return
+		}
+		for i := 0; i < l; i++ {
+			k, v = zeroK, zeroV
+			if err := s.Next(&k); err != nil {
+				m.err = err
+				return
+			}
+			if err := s.Next(&v); err != nil {
+				m.err = err
+				return
+			}
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+// Err returns the first error that stopped the iteration, if any.
+func (m *MapReader[K, V]) Err() error {
+	return m.err
+}
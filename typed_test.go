@@ -0,0 +1,263 @@
+package msgpack_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/shamaton/msgpack/v3"
+	"github.com/shamaton/msgpack/v3/def"
+	tu "github.com/shamaton/msgpack/v3/internal/common/testutil"
+)
+
+type typedItem struct {
+	ID   int
+	Name string
+}
+
+func TestUnmarshalAs(t *testing.T) {
+	b, err := msgpack.Marshal(typedItem{ID: 1, Name: "a"})
+	tu.NoError(t, err)
+
+	v, err := msgpack.UnmarshalAs[typedItem](b)
+	tu.NoError(t, err)
+	tu.Equal(t, v, typedItem{ID: 1, Name: "a"})
+
+	p, err := msgpack.UnmarshalAs[*typedItem](b)
+	tu.NoError(t, err)
+	tu.Equal(t, *p, typedItem{ID: 1, Name: "a"})
+
+	_, err = msgpack.UnmarshalAs[int](b)
+	tu.Error(t, err)
+
+	_, err = msgpack.UnmarshalAs[int](nil)
+	tu.IsError(t, err, def.ErrNoData)
+}
+
+func TestReadAs(t *testing.T) {
+	b, err := msgpack.Marshal(map[string]int{"a": 1})
+	tu.NoError(t, err)
+
+	v, err := msgpack.ReadAs[map[string]int](bytes.NewReader(b))
+	tu.NoError(t, err)
+	tu.EqualMap(t, v, map[string]int{"a": 1})
+
+	_, err = msgpack.ReadAs[string](bytes.NewReader(b))
+	tu.Error(t, err)
+}
+
+func TestTypedWithOptions(t *testing.T) {
+	item := typedItem{ID: 1, Name: "abc"}
+	b, err := msgpack.EncodeOptions{StructAsArray: true}.Marshal(item)
+	tu.NoError(t, err)
+	o := msgpack.DecodeOptions{StructAsArray: true}
+	limited := msgpack.DecodeOptions{StructAsArray: true, MaxStringLen: 2}
+
+	t.Run("UnmarshalAsWith", func(t *testing.T) {
+		v, err := msgpack.UnmarshalAsWith[typedItem](o, b)
+		tu.NoError(t, err)
+		tu.Equal(t, v, item)
+
+		_, err = msgpack.UnmarshalAsWith[typedItem](limited, b)
+		tu.IsError(t, err, def.ErrMaxStringLenExceeded)
+	})
+	t.Run("ReadAsWith", func(t *testing.T) {
+		v, err := msgpack.ReadAsWith[typedItem](o, bytes.NewReader(b))
+		tu.NoError(t, err)
+		tu.Equal(t, v, item)
+
+		_, err = msgpack.ReadAsWith[typedItem](limited, bytes.NewReader(b))
+		tu.IsError(t, err, def.ErrMaxStringLenExceeded)
+	})
+	t.Run("NewTypedDecoderWith", func(t *testing.T) {
+		data := append(append([]byte{}, b...), b...)
+		d := msgpack.NewTypedDecoderWith[typedItem](o, bytes.NewReader(data))
+		n := 0
+		for v, err := range d.All() {
+			tu.NoError(t, err)
+			tu.Equal(t, v, item)
+			n++
+		}
+		tu.Equal(t, n, 2)
+
+		d = msgpack.NewTypedDecoderWith[typedItem](limited, bytes.NewReader(data))
+		_, err := d.Decode()
+		tu.IsError(t, err, def.ErrMaxStringLenExceeded)
+	})
+}
+
+func TestTypedDecoder(t *testing.T) {
+	buf := bytes.Buffer{}
+	for i := 0; i < 3; i++ {
+		tu.NoError(t, msgpack.MarshalWrite(&buf, typedItem{ID: i, Name: "x"}))
+	}
+	data := buf.Bytes()
+
+	t.Run("Decode", func(t *testing.T) {
+		d := msgpack.NewTypedDecoder[typedItem](bytes.NewReader(data))
+		for i := 0; i < 3; i++ {
+			v, err := d.Decode()
+			tu.NoError(t, err)
+			tu.Equal(t, v, typedItem{ID: i, Name: "x"})
+		}
+		_, err := d.Decode()
+		tu.IsError(t, err, io.EOF)
+	})
+	t.Run("All", func(t *testing.T) {
+		d := msgpack.NewTypedDecoder[typedItem](bytes.NewReader(data))
+		n := 0
+		for v, err := range d.All() {
+			tu.NoError(t, err)
+			tu.Equal(t, v.ID, n)
+			n++
+		}
+		tu.Equal(t, n, 3)
+	})
+	t.Run("Break", func(t *testing.T) {
+		d := msgpack.NewTypedDecoder[typedItem](bytes.NewReader(data))
+		for range d.All() {
+			break
+		}
+		v, err := d.Decode()
+		tu.NoError(t, err)
+		tu.Equal(t, v.ID, 1)
+	})
+	t.Run("UnexpectedEOF", func(t *testing.T) {
+		d := msgpack.NewTypedDecoder[typedItem](bytes.NewReader(data[:len(data)-1]))
+		var last error
+		for _, err := range d.All() {
+			last = err
+		}
+		tu.IsError(t, last, io.ErrUnexpectedEOF)
+	})
+	t.Run("TypeError", func(t *testing.T) {
+		d := msgpack.NewTypedDecoder[string](bytes.NewReader(data))
+		_, err := d.Decode()
+		if err == nil || errors.Is(err, io.EOF) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	t.Run("NilReader", func(t *testing.T) {
+		d := msgpack.NewTypedDecoder[int](nil)
+		_, err := d.Decode()
+		tu.IsError(t, err, def.ErrNoData)
+	})
+}
+
+func TestReadArray(t *testing.T) {
+	items := make([]typedItem, 100)
+	for i := range items {
+		items[i] = typedItem{ID: i, Name: "x"}
+	}
+	items[5].Name = ""
+	b, err := msgpack.Marshal(items)
+	tu.NoError(t, err)
+
+	t.Run("All", func(t *testing.T) {
+		var got []typedItem
+		for v, err := range msgpack.ReadArray[typedItem](bytes.NewReader(b)) {
+			tu.NoError(t, err)
+			got = append(got, v)
+		}
+		tu.EqualSlice(t, got, items)
+	})
+	t.Run("Break", func(t *testing.T) {
+		r := bytes.NewReader(b)
+		n := 0
+		for range msgpack.ReadArray[typedItem](r) {
+			n++
+			if n == 2 {
+				break
+			}
+		}
+		tu.Equal(t, n, 2)
+	})
+	t.Run("Nil", func(t *testing.T) {
+		for range msgpack.ReadArray[int](bytes.NewReader([]byte{def.Nil})) {
+			t.Fatal("nil should have no elements")
+		}
+	})
+	t.Run("NotArray", func(t *testing.T) {
+		n := 0
+		for _, err := range msgpack.ReadArray[int](bytes.NewReader([]byte{0x80})) {
+			tu.Error(t, err)
+			n++
+		}
+		tu.Equal(t, n, 1)
+	})
+	t.Run("ElementError", func(t *testing.T) {
+		var last error
+		for _, err := range msgpack.ReadArray[int](bytes.NewReader([]byte{0x92, 0x01, 0xa1, 'a'})) {
+			last = err
+		}
+		tu.Error(t, last)
+	})
+	t.Run("ErrorPath", func(t *testing.T) {
+		// the path is the one UnmarshalRead reports for the whole array
+		b := msgpack.StructAsArray
+		defer func() {
+			msgpack.StructAsArray = b
+		}()
+		msgpack.StructAsArray = false
+
+		data := []byte{0x92, 0x81, 0xa2, 'I', 'D', 0x01, 0x81, 0xa2, 'I', 'D', 0xa1, 'x'}
+		var want *def.DecodeError
+		var items []typedItem
+		if !errors.As(msgpack.UnmarshalRead(bytes.NewReader(data), &items), &want) {
+			t.Fatal("not DecodeError")
+		}
+		var last error
+		for _, err := range msgpack.ReadArray[typedItem](bytes.NewReader(data)) {
+			last = err
+		}
+		var got *def.DecodeError
+		if !errors.As(last, &got) {
+			t.Fatalf("not DecodeError: %v", last)
+		}
+		tu.Equal(t, got.Path, "[1].ID")
+		tu.Equal(t, got.Path, want.Path)
+		tu.Equal(t, got.Error(), want.Error())
+	})
+}
+
+func TestMapReader(t *testing.T) {
+	m := map[string]typedItem{"a": {ID: 1}, "b": {ID: 2, Name: "b"}}
+	b, err := msgpack.Marshal(m)
+	tu.NoError(t, err)
+
+	t.Run("All", func(t *testing.T) {
+		r := msgpack.NewMapReader[string, typedItem](bytes.NewReader(b))
+		got := map[string]typedItem{}
+		for k, v := range r.All() {
+			got[k] = v
+		}
+		tu.NoError(t, r.Err())
+		tu.EqualMap(t, got, m)
+	})
+	t.Run("KeyError", func(t *testing.T) {
+		r := msgpack.NewMapReader[int, typedItem](bytes.NewReader(b))
+		for range r.All() {
+			t.Fatal("should not yield")
+		}
+		tu.Error(t, r.Err())
+	})
+	t.Run("ValueErrorPath", func(t *testing.T) {
+		r := msgpack.NewMapReader[string, int](bytes.NewReader(b))
+		for range r.All() {
+			t.Fatal("should not yield")
+		}
+		var e *def.DecodeError
+		if !errors.As(r.Err(), &e) {
+			t.Fatalf("not DecodeError: %v", r.Err())
+		}
+		tu.Equal(t, e.Path == `["a"]` || e.Path == `["b"]`, true)
+	})
+	t.Run("NotMap", func(t *testing.T) {
+		r := msgpack.NewMapReader[int, int](bytes.NewReader([]byte{0x90}))
+		for range r.All() {
+			t.Fatal("should not yield")
+		}
+		tu.Error(t, r.Err())
+	})
+}